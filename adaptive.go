@@ -0,0 +1,160 @@
+package bitmap
+
+import (
+	"math/bits"
+	"strconv"
+)
+
+// Representation is a way to store a set of integers
+type Representation int
+
+const (
+	// Dense is NBitmap, one bit for every integer
+	Dense Representation = iota
+	// SortedArray is EliasFano, a compact sorted sequence
+	SortedArray
+	// Intervals is IntervalSet, sorted runs of consecutive integers
+	Intervals
+	// Compressed is EWAH, run length compressed words
+	Compressed
+)
+
+// String return name of representation
+func (r Representation) String() string {
+	switch r {
+	case Dense:
+		return "Dense"
+	case SortedArray:
+		return "SortedArray"
+	case Intervals:
+		return "Intervals"
+	case Compressed:
+		return "Compressed"
+	}
+	return "Representation(" + strconv.Itoa(int(r)) + ")"
+}
+
+// Membership is the read interface shared by all representations
+type Membership interface {
+	Has(x int) bool
+	Len() int
+}
+
+// Stats is the statistics of a NBitmap
+type Stats struct {
+	Cardinality  int // numbers in bitmap
+	Max          int // the largest element, -1 if empty
+	Words        int // words up to the largest element
+	NonZeroWords int // words with at least one element
+	Runs         int // runs of consecutive elements
+	CleanRuns    int // runs of all zero or all one words
+	LiteralWords int // words neither all zero nor all one
+}
+
+// Choice is the cheapest representation of a bitmap
+type Choice struct {
+	Representation Representation
+	Bytes          int // estimated bytes of the representation
+	DenseBytes     int // bytes of the NBitmap
+	Savings        int // DenseBytes - Bytes
+}
+
+// Stats return the statistics of bitmap
+func (n *NBitmap) Stats() Stats {
+	s := Stats{Cardinality: n.len, Max: -1}
+	var prev bitInt // previous word
+	cleanKind := -1 // 0 or 1 for the current clean run, -1 for none
+	next := 0       // the next page
+	cleanRuns := 0  // clean runs up to the last non zero word
+	n.rangePages(func(p int, pg *page) bool {
+		if p > next {
+			// released pages are a run of zero words
+			if cleanKind != 0 {
+				s.CleanRuns++
+				cleanKind = 0
+			}
+			prev = 0
+		}
+		next = p + 1
+		for i, word := range pg.words {
+			if word != 0 {
+				s.NonZeroWords++
+				s.Max = p*pageBits + i*bitSize + bits.Len64(uint64(word)) - 1
+			}
+			// a run starts at every element whose previous integer is not an element
+			carry := prev >> (bitSize - 1)
+			s.Runs += bits.OnesCount64(uint64(word &^ (word<<1 | carry)))
+			switch word {
+			case 0, ^bitInt(0):
+				kind := int(word & 1)
+				if kind != cleanKind {
+					s.CleanRuns++
+					cleanKind = kind
+				}
+			default:
+				s.LiteralWords++
+				cleanKind = -1
+			}
+			if word != 0 {
+				cleanRuns = s.CleanRuns
+			}
+			prev = word
+		}
+		return true
+	})
+	// zero words after the largest element are not counted
+	s.CleanRuns = cleanRuns
+	if s.Max >= 0 {
+		s.Words = s.Max/bitSize + 1
+	}
+	return s
+}
+
+// Best return the representation of n with the least estimated bytes
+func Best(n *NBitmap) Choice {
+	s := n.Stats()
+	dense := s.Words * bitSize / 8
+	costs := [...]int{
+		Dense:       dense,
+		SortedArray: eliasFanoBytes(s.Cardinality, s.Max+1),
+		Intervals:   s.Runs * 2 * strconv.IntSize / 8,
+		Compressed:  (s.LiteralWords + s.CleanRuns + 1) * bitSize / 8,
+	}
+	c := Choice{Representation: Dense, Bytes: dense, DenseBytes: dense}
+	for r, cost := range costs {
+		if cost < c.Bytes {
+			c.Representation = Representation(r)
+			c.Bytes = cost
+		}
+	}
+	c.Savings = c.DenseBytes - c.Bytes
+	return c
+}
+
+// Optimize convert the bitmap to the representation chosen by Best
+// the bitmap itself is returned if Dense is the cheapest
+func (n *NBitmap) Optimize() (Membership, Choice) {
+	c := Best(n)
+	switch c.Representation {
+	case SortedArray:
+		return NewEliasFano(n), c
+	case Intervals:
+		return NewIntervalFromBitmap(n), c
+	case Compressed:
+		return NewEWAHFromBitmap(n), c
+	}
+	return n, c
+}
+
+// eliasFanoBytes return the estimated bytes of EliasFano of n elements less than u
+func eliasFanoBytes(n int, u int) int {
+	if n == 0 {
+		return 0
+	}
+	l := 0
+	if u > n {
+		l = bits.Len64(uint64(u/n)) - 1
+	}
+	// low parts, high parts and select samples
+	return (n*l+n+u>>uint(l)+1+7)/8 + (n+u>>uint(l))/efSample*strconv.IntSize/8
+}
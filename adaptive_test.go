@@ -0,0 +1,69 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	b := bitmap.New()
+	for _, x := range []int{1, 2, 3, 5, 63, 64, 65, 1000} {
+		b.Add(x)
+	}
+	s := b.Stats()
+	if s.Cardinality != 8 || s.Max != 1000 || s.Runs != 4 || s.Words != 16 {
+		t.Errorf("TestStats failed. Got %+v", s)
+	}
+	if s := bitmap.New().Stats(); s.Max != -1 || s.Runs != 0 || s.NonZeroWords != 0 {
+		t.Errorf("TestStats empty failed. Got %+v", s)
+	}
+}
+
+func TestBest(t *testing.T) {
+	dense := bitmap.New()
+	for i := 0; i < 100000; i += 2 {
+		dense.Add(i)
+	}
+	sparse := bitmap.New()
+	for i := 0; i < 1000000; i += 10007 {
+		sparse.Add(i)
+	}
+	runs := bitmap.New()
+	for i := 10000; i < 500000; i++ {
+		runs.Add(i)
+	}
+	mixed := bitmap.New()
+	for i := 0; i < 200000; i++ {
+		if i < 100000 || i%3 == 0 {
+			mixed.Add(i)
+		}
+	}
+	cases := []struct {
+		name     string
+		b        *bitmap.NBitmap
+		expected bitmap.Representation
+	}{
+		{"dense", dense, bitmap.Dense},
+		{"sparse", sparse, bitmap.SortedArray},
+		{"runs", runs, bitmap.Intervals},
+		{"mixed", mixed, bitmap.Compressed},
+	}
+	for _, c := range cases {
+		choice := bitmap.Best(c.b)
+		if choice.Representation != c.expected {
+			t.Errorf("TestBest %s failed. Expected %s, Got %s", c.name, c.expected, choice.Representation)
+		}
+		if choice.Savings != choice.DenseBytes-choice.Bytes || choice.Savings < 0 {
+			t.Errorf("TestBest %s failed. Got %+v", c.name, choice)
+		}
+		m, _ := c.b.Optimize()
+		if m.Len() != c.b.Len() {
+			t.Errorf("TestBest %s Optimize failed. Expected %d, Got %d", c.name, c.b.Len(), m.Len())
+		}
+		for x := 0; x < 1000000; x += 997 {
+			if m.Has(x) != c.b.Has(x) {
+				t.Fatalf("TestBest %s Optimize failed for %d.", c.name, x)
+			}
+		}
+	}
+}
@@ -4,35 +4,54 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"math/bits"
 )
 
-type bitInt uint
+// bitInt is the word of bitmaps, it's 64 bits on all platforms
+// so serialized bytes and packed counters are the same everywhere
+type bitInt uint64
 
 const (
-	bitSize    = 32 << (^bitInt(0) >> 63)
+	bitSize    = 64
 	bitmapSize = 4
+	maxN       = 1<<(bitSize-1) - 1 // the largest count of CBitmap and RCBitmap, math.MaxInt64 on all platforms
 )
 
 // Bitmap is the interface of bitSet
 type Bitmap interface {
-	Add(x int)    // add x to bitmap
-	Has(x int)    // return true if x is in bitmap
-	Remove(x int) // remove x in bitmap
-	Len() int     // return length of bitmap
-	Clear()       // clear bitmap to free memory
+	Add(x int)      // add x to bitmap
+	Has(x int) bool // return true if x is in bitmap
+	Remove(x int)   // remove x in bitmap
+	Len() int       // return length of bitmap
+	Clear()         // clear bitmap, memory is kept until Shrink
+}
+
+// BudgetBitmap is the interface of bitSet whose memory can be limited
+type BudgetBitmap interface {
+	Bitmap
+	TryAdd(x int) error  // add x to bitmap, ErrBudget if the memory budget is exceeded
+	SetBudget(bytes int) // limit the memory to bytes, 0 for no limit
+	MemoryUsage() int    // return estimated bytes used
+	Shrink()             // free memory not used by elements
 }
 
 // NBitmap is a normal bitSet
+// words are stored in pages allocated when they get the first element
+// and released when they become empty
 type NBitmap struct {
-	len   int
-	words []bitInt
+	len       int
+	pages     []*page       // nil for pages without elements
+	far       map[int]*page // pages far after the page table
+	farKeys   []int         // keys of far in increasing order
+	spare     *page         // the last released page, reused by the next allocation
+	allocated int           // pages allocated, including spare
+	budget    int           // max bytes of memory, 0 for no limit
 }
 
 // New return a new bitmap
 func New() *NBitmap {
 	return &NBitmap{
-		len:   0,
-		words: make([]bitInt, bitmapSize),
+		len: 0,
 	}
 }
 
@@ -40,18 +59,13 @@ func New() *NBitmap {
 func (n *NBitmap) String() string {
 	var buf bytes.Buffer
 	buf.WriteByte('{')
-	for i, word := range n.words {
-		if word != 0 {
-			for j := 0; j < bitSize; j++ {
-				if word&(1<<bitInt(j)) != 0 {
-					if buf.Len() > len("{") {
-						buf.WriteByte(' ')
-					}
-					fmt.Fprintf(&buf, "%d", bitSize*i+j)
-				}
-			}
+	n.Range(func(x int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
 		}
-	}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
 	buf.WriteByte('}')
 	return buf.String()
 }
@@ -66,24 +80,50 @@ func (n *NBitmap) Has(x int) bool {
 	if x < 0 {
 		return false
 	}
-	word, bit := x/bitSize, bitInt(x%bitSize)
-	return word < len(n.words) && n.words[word]&(1<<bit) != 0
+	p, word, bit := x/pageBits, x%pageBits/bitSize, bitInt(x%bitSize)
+	var pg *page
+	if p < len(n.pages) {
+		pg = n.pages[p]
+	} else {
+		pg = n.far[p]
+	}
+	return pg != nil && pg.words[word]&(1<<bit) != 0
 }
 
 // Add add x to the bitmap
+// x is not added if a new page exceeds the memory budget, TryAdd return the error
 func (n *NBitmap) Add(x int) {
+	n.TryAdd(x)
+}
+
+// TryAdd add x to the bitmap
+// return ErrBudget if a new page exceeds the memory budget
+func (n *NBitmap) TryAdd(x int) error {
 	if x < 0 {
-		return
+		return nil
 	}
-	word, bit := x/bitSize, bitInt(x%bitSize)
-	if word >= len(n.words) {
-		n.words = append(n.words, make([]bitInt, word+1-len(n.words))...)
+	p, word, bit := x/pageBits, x%pageBits/bitSize, bitInt(x%bitSize)
+	var pg *page
+	if p < len(n.pages) {
+		pg = n.pages[p]
+		if pg == nil && n.spare != nil {
+			// the spare page is already counted in the memory
+			pg, n.pages[p], n.spare = n.spare, n.spare, nil
+		}
+	}
+	if pg == nil {
+		var err error
+		if pg, err = n.addPage(p); err != nil {
+			return err
+		}
 	}
 	num := bitInt(1 << bit)
-	if n.words[word]&num == 0 {
+	if pg.words[word]&num == 0 {
 		n.len++
-		n.words[word] |= num
+		pg.count++
+		pg.words[word] |= num
 	}
+	return nil
 }
 
 // Remove remove x in bitmap
@@ -91,93 +131,149 @@ func (n *NBitmap) Remove(x int) {
 	if x < 0 {
 		return
 	}
-	word, bit := x/bitSize, bitInt(x%bitSize)
-	if word < len(n.words) {
+	p, word, bit := x/pageBits, x%pageBits/bitSize, bitInt(x%bitSize)
+	var pg *page
+	if p < len(n.pages) {
+		pg = n.pages[p]
+	} else {
+		pg = n.far[p]
+	}
+	if pg != nil {
 		num := bitInt(1 << bit)
-		if n.words[word]&num != 0 {
+		if pg.words[word]&num != 0 {
 			n.len--
-			n.words[word] &^= num
+			pg.count--
+			pg.words[word] &^= num
+			if pg.count == 0 {
+				if p < len(n.pages) && n.spare == nil {
+					// same as release, without the call
+					n.spare, n.pages[p] = pg, nil
+				} else {
+					n.release(p)
+				}
+			}
 		}
 	}
 }
 
 // Clear make the bitmap empty
+// the page table and the budget are kept, pages are released
 func (n *NBitmap) Clear() {
+	for p := range n.pages {
+		n.pages[p] = nil
+	}
+	pages, budget := n.pages[:0], n.budget
 	*n = *New()
+	n.pages, n.budget = pages, budget
 }
 
 // Copy return a copy bitmap
 func (n *NBitmap) Copy() *NBitmap {
 	new := NBitmap{}
 	new.len = n.len
-	new.words = make([]bitInt, len(n.words))
-	copy(new.words, n.words)
+	new.budget = n.budget
+	new.pages = make([]*page, len(n.pages))
+	if len(n.far) > 0 {
+		new.far = make(map[int]*page, len(n.far))
+		new.farKeys = append([]int(nil), n.farKeys...)
+	}
+	n.rangePages(func(p int, pg *page) bool {
+		new.allocated++
+		cp := *pg
+		if p < len(n.pages) {
+			new.pages[p] = &cp
+		} else {
+			new.far[p] = &cp
+		}
+		return true
+	})
 	return &new
 }
 
+// Range call f with every element in increasing order
+// stop if f return false
+func (n *NBitmap) Range(f func(x int) bool) {
+	n.rangePages(func(p int, pg *page) bool {
+		for i, word := range pg.words {
+			for word != 0 {
+				j := bits.TrailingZeros64(uint64(word))
+				if !f(p*pageBits + bitSize*i + j) {
+					return false
+				}
+				word &^= 1 << bitInt(j)
+			}
+		}
+		return true
+	})
+}
+
 // Union n = n | c
 // elements in n or c
-func (n *NBitmap) Union(c *NBitmap) {
-	length := 0
-	for i, cword := range c.words {
-		if i >= len(n.words) {
-			length = i
-			break
-		}
-		n.words[i] |= cword
-	}
-	if length != 0 {
-		n.words = append(n.words, c.words[length:]...)
-	}
+// return ErrBudget if a new page exceeds the memory budget, pages of c before it are merged
+func (n *NBitmap) Union(c *NBitmap) error {
+	var err error
+	c.rangePages(func(p int, cpg *page) bool {
+		err = n.mergePage(p, cpg, false)
+		return err == nil
+	})
+	return err
 }
 
 // Intersect n = n & c
 // elements both in n and c
 func (n *NBitmap) Intersect(c *NBitmap) {
-	for i, cwords := range c.words {
-		if i >= len(n.words) {
-			break
+	n.rangePages(func(p int, pg *page) bool {
+		cpg := c.lookup(p)
+		if cpg == nil {
+			pg.words = [pageWords]bitInt{}
+			return true
 		}
-		n.words[i] &= cwords
-	}
-	if len(c.words) < len(n.words) {
-		n.words = n.words[:len(c.words)]
-	}
+		for i, cword := range cpg.words {
+			pg.words[i] &= cword
+		}
+		return true
+	})
+	n.recount()
 }
 
 // Except n = n - c
 // elements only in n
 func (n *NBitmap) Except(c *NBitmap) {
-	for i, cwords := range c.words {
-		if i >= len(n.words) {
-			break
+	n.rangePages(func(p int, pg *page) bool {
+		if cpg := c.lookup(p); cpg != nil {
+			for i, cword := range cpg.words {
+				pg.words[i] &^= cword
+			}
 		}
-		n.words[i] &^= cwords
-	}
+		return true
+	})
+	n.recount()
 }
 
 // SymExcept n = (n - c) | (c - n)
 // elements only in n or only in c
-func (n *NBitmap) SymExcept(c *NBitmap) {
-	length := 0
-	for i, cwords := range c.words {
-		if i >= len(n.words) {
-			length = i
-			break
-		}
-		n.words[i] = (n.words[i] &^ cwords) | (cwords &^ n.words[i])
-	}
-	if length != 0 {
-		n.words = append(n.words, c.words[length:]...)
+// return ErrBudget if a new page exceeds the memory budget, pages of c before it are merged
+func (n *NBitmap) SymExcept(c *NBitmap) error {
+	if c == n {
+		n.Clear()
+		return nil
 	}
+	var err error
+	c.rangePages(func(p int, cpg *page) bool {
+		err = n.mergePage(p, cpg, true)
+		return err == nil
+	})
+	n.trim()
+	return err
 }
 
 // RBitmap is a bitSet count in [start, end)
 type RBitmap struct {
-	len   int
-	start int
-	end   int
-	words []bitInt
+	len    int
+	start  int
+	end    int
+	words  []bitInt
+	budget int // max bytes of memory, 0 for no limit
 }
 
 // NewR return a new bitmap, count in [start, end)
@@ -229,20 +325,31 @@ func (r *RBitmap) Has(x int) bool {
 }
 
 // Add add x to the bitmap
+// x is not added if growing words exceeds the memory budget, TryAdd return the error
 func (r *RBitmap) Add(x int) {
+	r.TryAdd(x)
+}
+
+// TryAdd add x to the bitmap
+// return ErrBudget if growing words exceeds the memory budget
+func (r *RBitmap) TryAdd(x int) error {
 	if x < r.start || x >= r.end {
-		return
+		return nil
 	}
 	x -= r.start
 	word, bit := x/bitSize, bitInt(x%bitSize)
 	if word >= len(r.words) {
-		r.words = append(r.words, make([]bitInt, word+1-len(r.words))...)
+		if !fitsWords(word+1, r.budget) {
+			return ErrBudget
+		}
+		r.words = growWords(r.words, word+1, r.budget)
 	}
 	num := bitInt(1 << bit)
 	if r.words[word]&num == 0 {
 		r.len++
 		r.words[word] |= num
 	}
+	return nil
 }
 
 // Remove remove x in bitmap
@@ -262,8 +369,12 @@ func (r *RBitmap) Remove(x int) {
 }
 
 // Clear make the bitmap empty
+// words and the budget are kept
 func (r *RBitmap) Clear() {
-	*r = *NewR(r.start, r.end)
+	for i := range r.words {
+		r.words[i] = 0
+	}
+	r.len = 0
 }
 
 // Copy return a copy bitmap
@@ -272,11 +383,26 @@ func (r *RBitmap) Copy() *RBitmap {
 	new.len = r.len
 	new.start = r.start
 	new.end = r.end
+	new.budget = r.budget
 	new.words = make([]bitInt, len(r.words))
 	copy(new.words, r.words)
 	return &new
 }
 
+// Range call f with every element in increasing order
+// stop if f return false
+func (r *RBitmap) Range(f func(x int) bool) {
+	for i, word := range r.words {
+		for word != 0 {
+			j := bits.TrailingZeros64(uint64(word))
+			if !f(r.start + bitSize*i + j) {
+				return
+			}
+			word &^= 1 << bitInt(j)
+		}
+	}
+}
+
 // Union r = r | c
 // elements in r or c
 // r must have the same range of c
@@ -295,6 +421,7 @@ func (r *RBitmap) Union(c *RBitmap) {
 	if length != 0 {
 		r.words = append(r.words, c.words[length:]...)
 	}
+	r.len = popcount(r.words)
 }
 
 // Intersect r = r & c
@@ -313,6 +440,7 @@ func (r *RBitmap) Intersect(c *RBitmap) {
 	if len(c.words) < len(r.words) {
 		r.words = r.words[:len(c.words)]
 	}
+	r.len = popcount(r.words)
 }
 
 // Except r = r - c
@@ -328,6 +456,7 @@ func (r *RBitmap) Except(c *RBitmap) {
 		}
 		r.words[i] &^= cwords
 	}
+	r.len = popcount(r.words)
 }
 
 // SymExcept r = (r - c) | (c - r)
@@ -348,6 +477,7 @@ func (r *RBitmap) SymExcept(c *RBitmap) {
 	if length != 0 {
 		r.words = append(r.words, c.words[length:]...)
 	}
+	r.len = popcount(r.words)
 }
 
 // CBitmap is a bitSet
@@ -355,14 +485,19 @@ type CBitmap struct {
 	len     int
 	n       bitInt
 	bitSize int
-	mask    int
+	mask    bitInt
 	numSize int
 	words   []bitInt
+	period  int // halve all counters after period increments, 0 to disable
+	adds    int // increments since last decay
+	budget  int // max bytes of memory, 0 for no limit
 }
 
-// NewC return a new bitmap
+// NewC return a new bitmap, every element can count to n
+// n must be in [1, maxN], or nil is returned, int is 32 bits on 386 and arm,
+// so n is at most math.MaxInt32 there
 func NewC(n int) *CBitmap {
-	if n <= 0 || n > (1<<(bitSize-1)-1) {
+	if n <= 0 || uint64(n) > maxN {
 		return nil
 	}
 	numSize := int(math.Log2(float64(n)))
@@ -371,7 +506,7 @@ func NewC(n int) *CBitmap {
 	c.n = bitInt(n)
 	c.numSize = numSize + 1
 	c.bitSize = bitSize / c.numSize
-	c.mask = (1 << bitInt(c.numSize)) - 1
+	c.mask = bitInt(1)<<bitInt(c.numSize) - 1
 	c.words = make([]bitInt, bitmapSize)
 	return &c
 }
@@ -380,20 +515,51 @@ func NewC(n int) *CBitmap {
 func (c *CBitmap) String() string {
 	var buf bytes.Buffer
 	buf.WriteByte('{')
+	c.Range(func(x int, count int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// CountString return formated string of bitmap with counts, like {10:3 100:1}
+func (c *CBitmap) CountString() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	c.Range(func(x int, count int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d:%d", x, count)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Format implement fmt.Formatter, %+v print the counts
+func (c *CBitmap) Format(f fmt.State, verb rune) {
+	formatCount(f, verb, c.String, c.CountString)
+}
+
+// Range call f with every element and its count in increasing order
+// stop if f return false
+func (c *CBitmap) Range(f func(x int, count int) bool) {
 	for i, word := range c.words {
-		if word != 0 {
-			for j := 0; j < c.bitSize; j += c.numSize {
-				if word&(bitInt(c.mask<<bitInt(j))) != 0 {
-					if buf.Len() > len("{") {
-						buf.WriteByte(' ')
-					}
-					fmt.Fprintf(&buf, "%d", c.bitSize*i+j/c.numSize)
-				}
+		if word == 0 {
+			continue
+		}
+		for j := 0; j < c.bitSize; j++ {
+			count := int((word >> bitInt(j*c.numSize)) & c.mask)
+			if count != 0 && !f(c.bitSize*i+j, count) {
+				return
 			}
 		}
 	}
-	buf.WriteByte('}')
-	return buf.String()
 }
 
 // Len return numbers in bitmap
@@ -407,25 +573,42 @@ func (c *CBitmap) Has(x int) bool {
 		return false
 	}
 	word, bit := x/c.bitSize, bitInt(x%c.bitSize*c.numSize)
-	return word < len(c.words) && c.words[word]&(1<<bit) != 0
+	return word < len(c.words) && c.words[word]&(c.mask<<bit) != 0
 }
 
 // Add add x to the bitmap
+// x is not added if growing words exceeds the memory budget, TryAdd return the error
 func (c *CBitmap) Add(x int) {
+	c.TryAdd(x)
+}
+
+// TryAdd add x to the bitmap
+// return ErrBudget if growing words exceeds the memory budget
+func (c *CBitmap) TryAdd(x int) error {
 	if x < 0 {
-		return
+		return nil
 	}
 	word, bit := x/c.bitSize, bitInt(x%c.bitSize*c.numSize)
 	if word >= len(c.words) {
-		c.words = append(c.words, make([]bitInt, word+1-len(c.words))...)
+		if !fitsWords(word+1, c.budget) {
+			return ErrBudget
+		}
+		c.words = growWords(c.words, word+1, c.budget)
 	}
-	numSize := bitInt(c.mask << bit)
+	numSize := c.mask << bit
 	if c.words[word]&numSize == 0 {
 		c.len++
 	}
 	if ((c.words[word] & numSize) >> bit) < c.n {
 		c.words[word] += 1 << bit
 	}
+	if c.period > 0 {
+		c.adds++
+		if c.adds >= c.period {
+			c.Halve()
+		}
+	}
+	return nil
 }
 
 // Remove remove x in bitmap
@@ -435,7 +618,7 @@ func (c *CBitmap) Remove(x int) {
 	}
 	word, bit := x/c.bitSize, bitInt(x%c.bitSize*c.numSize)
 	if word < len(c.words) {
-		numSize := bitInt(c.mask << bit)
+		numSize := c.mask << bit
 		if c.words[word]&numSize != 0 {
 			c.words[word] -= 1 << bit
 			if c.words[word]&numSize == 0 {
@@ -454,7 +637,7 @@ func (c *CBitmap) Count(x int) int {
 	if word >= len(c.words) {
 		return 0
 	}
-	numSize := bitInt(c.mask << bit)
+	numSize := c.mask << bit
 	return int((numSize & c.words[word]) >> bit)
 }
 
@@ -465,7 +648,7 @@ func (c *CBitmap) RemoveAll(x int) {
 	}
 	word, bit := x/c.bitSize, bitInt(x%c.bitSize*c.numSize)
 	if word < len(c.words) {
-		numSize := bitInt(c.mask << bit)
+		numSize := c.mask << bit
 		if c.words[word]&numSize != 0 {
 			c.len--
 			c.words[word] &^= numSize
@@ -473,9 +656,63 @@ func (c *CBitmap) RemoveAll(x int) {
 	}
 }
 
+// Decay divide every count in bitmap by 2^k
+// all counters are shifted in one pass over words,
+// elements whose count drops to zero are removed
+func (c *CBitmap) Decay(k int) {
+	if k <= 0 {
+		return
+	}
+	c.adds = 0
+	if k >= c.numSize {
+		for i := range c.words {
+			c.words[i] = 0
+		}
+		c.len = 0
+		return
+	}
+	keep := fieldPattern(c.bitSize, c.numSize, c.mask>>bitInt(k))
+	low := fieldPattern(c.bitSize, c.numSize, 1)
+	c.len = 0
+	for i, word := range c.words {
+		if word == 0 {
+			continue
+		}
+		// drop the bits shifted in from the neighboring field
+		word = (word >> bitInt(k)) & keep
+		c.words[i] = word
+		// fold every field into its lowest bit to count nonzero fields
+		nonzero := word
+		for j := 1; j < c.numSize-k; j++ {
+			nonzero |= word >> bitInt(j)
+		}
+		c.len += bits.OnesCount64(uint64(nonzero & low))
+	}
+}
+
+// Halve divide every count in bitmap by 2
+func (c *CBitmap) Halve() {
+	c.Decay(1)
+}
+
+// SetPeriod make the bitmap halve all counts after every period adds
+// period <= 0 disable the automatic decay
+func (c *CBitmap) SetPeriod(period int) {
+	if period < 0 {
+		period = 0
+	}
+	c.period = period
+	c.adds = 0
+}
+
 // Clear make the bitmap empty
+// words, the period and the budget are kept
 func (c *CBitmap) Clear() {
-	*c = *NewC(int(c.n))
+	for i := range c.words {
+		c.words[i] = 0
+	}
+	c.len = 0
+	c.adds = 0
 }
 
 // Copy return a copy bitmap
@@ -486,25 +723,49 @@ func (c *CBitmap) Copy() *CBitmap {
 	new.mask = c.mask
 	new.bitSize = c.bitSize
 	new.numSize = c.numSize
+	new.period = c.period
+	new.adds = c.adds
+	new.budget = c.budget
 	new.words = make([]bitInt, len(c.words))
 	copy(new.words, c.words)
 	return &new
 }
 
+// popcount return numbers of bits set in words
+func popcount(words []bitInt) int {
+	count := 0
+	for _, word := range words {
+		count += bits.OnesCount64(uint64(word))
+	}
+	return count
+}
+
+// fieldPattern return a word with v repeated in each of the fields
+// fields of size numSize packed into one word
+func fieldPattern(fields int, numSize int, v bitInt) bitInt {
+	var word bitInt
+	for i := 0; i < fields; i++ {
+		word |= v << bitInt(i*numSize)
+	}
+	return word
+}
+
 // RCBitmap is a bitSet count in [start, end)
 type RCBitmap struct {
 	len        int
 	n          bitInt
 	start, end int
 	bitSize    int
-	mask       int
+	mask       bitInt
 	numSize    int
 	words      []bitInt
+	budget     int // max bytes of memory, 0 for no limit
 }
 
-// NewRC return a new bitmap count [start, end)
+// NewRC return a new bitmap count [start, end), every element can count to n
+// n is limited as NewC
 func NewRC(start int, end int, n int) *RCBitmap {
-	if n <= 0 || n > (1<<(bitSize-1)-1) || start >= end {
+	if n <= 0 || uint64(n) > maxN || start >= end {
 		return nil
 	}
 	numSize := int(math.Log2(float64(n)))
@@ -514,7 +775,7 @@ func NewRC(start int, end int, n int) *RCBitmap {
 	rc.start, rc.end = start, end
 	rc.numSize = numSize + 1
 	rc.bitSize = bitSize / rc.numSize
-	rc.mask = (1 << bitInt(rc.numSize)) - 1
+	rc.mask = bitInt(1)<<bitInt(rc.numSize) - 1
 	rc.words = make([]bitInt, bitmapSize)
 	return &rc
 }
@@ -523,20 +784,51 @@ func NewRC(start int, end int, n int) *RCBitmap {
 func (rc *RCBitmap) String() string {
 	var buf bytes.Buffer
 	buf.WriteByte('{')
+	rc.Range(func(x int, count int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// CountString return formated string of bitmap with counts, like {10:3 100:1}
+func (rc *RCBitmap) CountString() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	rc.Range(func(x int, count int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d:%d", x, count)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Format implement fmt.Formatter, %+v print the counts
+func (rc *RCBitmap) Format(f fmt.State, verb rune) {
+	formatCount(f, verb, rc.String, rc.CountString)
+}
+
+// Range call f with every element and its count in increasing order
+// stop if f return false
+func (rc *RCBitmap) Range(f func(x int, count int) bool) {
 	for i, word := range rc.words {
-		if word != 0 {
-			for j := 0; j < rc.bitSize; j += rc.numSize {
-				if word&(bitInt(rc.mask<<bitInt(j))) != 0 {
-					if buf.Len() > len("{") {
-						buf.WriteByte(' ')
-					}
-					fmt.Fprintf(&buf, "%d", rc.start+rc.bitSize*i+j/rc.numSize)
-				}
+		if word == 0 {
+			continue
+		}
+		for j := 0; j < rc.bitSize; j++ {
+			count := int((word >> bitInt(j*rc.numSize)) & rc.mask)
+			if count != 0 && !f(rc.start+rc.bitSize*i+j, count) {
+				return
 			}
 		}
 	}
-	buf.WriteByte('}')
-	return buf.String()
 }
 
 // Len return numbers in bitmap
@@ -551,26 +843,37 @@ func (rc *RCBitmap) Has(x int) bool {
 	}
 	x -= rc.start
 	word, bit := x/rc.bitSize, bitInt(x%rc.bitSize*rc.numSize)
-	return word < len(rc.words) && rc.words[word]&(1<<bit) != 0
+	return word < len(rc.words) && rc.words[word]&(rc.mask<<bit) != 0
 }
 
 // Add add x to the bitmap
+// x is not added if growing words exceeds the memory budget, TryAdd return the error
 func (rc *RCBitmap) Add(x int) {
+	rc.TryAdd(x)
+}
+
+// TryAdd add x to the bitmap
+// return ErrBudget if growing words exceeds the memory budget
+func (rc *RCBitmap) TryAdd(x int) error {
 	if x < rc.start || x >= rc.end {
-		return
+		return nil
 	}
 	x -= rc.start
 	word, bit := x/rc.bitSize, bitInt(x%rc.bitSize*rc.numSize)
 	if word >= len(rc.words) {
-		rc.words = append(rc.words, make([]bitInt, word+1-len(rc.words))...)
+		if !fitsWords(word+1, rc.budget) {
+			return ErrBudget
+		}
+		rc.words = growWords(rc.words, word+1, rc.budget)
 	}
-	numSize := bitInt(rc.mask << bit)
+	numSize := rc.mask << bit
 	if rc.words[word]&numSize == 0 {
 		rc.len++
 	}
 	if ((rc.words[word] & numSize) >> bit) < rc.n {
 		rc.words[word] += 1 << bit
 	}
+	return nil
 }
 
 // Remove remove x in bitmap
@@ -581,7 +884,7 @@ func (rc *RCBitmap) Remove(x int) {
 	x -= rc.start
 	word, bit := x/rc.bitSize, bitInt(x%rc.bitSize*rc.numSize)
 	if word < len(rc.words) {
-		numSize := bitInt(rc.mask << bit)
+		numSize := rc.mask << bit
 		if rc.words[word]&numSize != 0 {
 			rc.words[word] -= 1 << bit
 			if rc.words[word]&numSize == 0 {
@@ -601,7 +904,7 @@ func (rc *RCBitmap) Count(x int) int {
 	if word >= len(rc.words) {
 		return 0
 	}
-	numSize := bitInt(rc.mask << bit)
+	numSize := rc.mask << bit
 	return int((numSize & rc.words[word]) >> bit)
 }
 
@@ -613,7 +916,7 @@ func (rc *RCBitmap) RemoveAll(x int) {
 	x -= rc.start
 	word, bit := x/rc.bitSize, bitInt(x%rc.bitSize*rc.numSize)
 	if word < len(rc.words) {
-		numSize := bitInt(rc.mask << bit)
+		numSize := rc.mask << bit
 		if rc.words[word]&numSize != 0 {
 			rc.len--
 			rc.words[word] &^= numSize
@@ -622,8 +925,12 @@ func (rc *RCBitmap) RemoveAll(x int) {
 }
 
 // Clear make the bitmap empty
+// words and the budget are kept
 func (rc *RCBitmap) Clear() {
-	*rc = *NewRC(rc.start, rc.end, int(rc.n))
+	for i := range rc.words {
+		rc.words[i] = 0
+	}
+	rc.len = 0
 }
 
 // Copy return a copy bitmap
@@ -635,6 +942,7 @@ func (rc *RCBitmap) Copy() *RCBitmap {
 	new.mask = rc.mask
 	new.bitSize = rc.bitSize
 	new.numSize = rc.numSize
+	new.budget = rc.budget
 	new.words = make([]bitInt, len(rc.words))
 	copy(new.words, rc.words)
 	return &new
@@ -2,6 +2,7 @@ package bitmap_test
 
 import (
 	"bitmap"
+	"math"
 	"testing"
 )
 
@@ -101,6 +102,30 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestRange(t *testing.T) {
+	b := bitmap.New()
+	b.Add(10000)
+	b.Add(0)
+	b.Add(63)
+	b.Add(64)
+	var xs []int
+	b.Range(func(x int) bool {
+		xs = append(xs, x)
+		return true
+	})
+	if len(xs) != 4 || xs[0] != 0 || xs[1] != 63 || xs[2] != 64 || xs[3] != 10000 {
+		t.Errorf("TestRange failed. Expected [0 63 64 10000], Got %v", xs)
+	}
+	xs = xs[:0]
+	b.Range(func(x int) bool {
+		xs = append(xs, x)
+		return x < 63
+	})
+	if len(xs) != 2 {
+		t.Errorf("TestRange stop failed. Expected [0 63], Got %v", xs)
+	}
+}
+
 func TestSets(t *testing.T) {
 	b := bitmap.New()
 	b.Add(-1)
@@ -120,6 +145,9 @@ func TestSets(t *testing.T) {
 	if bb.String() != "{0 1 2 3 4 5 6 10000}" {
 		t.Errorf("TestSets Union failed. Expected {0 1 2 3 4 5 6 10000}, Got %s", bb.String())
 	}
+	if bb.Len() != 8 {
+		t.Errorf("TestSets Union Len failed. Expected 8, Got %d", bb.Len())
+	}
 	bb = b.Copy()
 	bb.Add(100001)
 	bb.Intersect(c)
@@ -138,6 +166,45 @@ func TestSets(t *testing.T) {
 	}
 }
 
+func TestPages(t *testing.T) {
+	const stray = 1 << 30
+	b := bitmap.New()
+	b.Add(1)
+	b.Add(stray)
+	if m := b.MemoryUsage(); m > 8192 || !b.Has(stray) || b.Len() != 2 || b.Stats().Max != stray {
+		t.Errorf("TestPages failed. Expected 2 pages, Got %d bytes", m)
+	}
+	b.Remove(stray)
+	if s := b.Stats(); s.Words != 1 || b.Has(stray) {
+		t.Errorf("TestPages Remove failed. Expected 1 word, Got %d words", s.Words)
+	}
+	c := bitmap.New()
+	for i := 0; i < 100000; i++ {
+		b.Add(i)
+		c.Add(i)
+	}
+	b.Except(c)
+	if s := b.Stats(); s.Words != 0 || b.Len() != 0 || b.String() != "{}" {
+		t.Errorf("TestPages Except failed. Expected no pages, Got %d words", s.Words)
+	}
+	b.Add(1<<23 + 1)
+	for i := 0; i < 20000; i++ {
+		b.Add(i * 1000)
+	}
+	prev, count := -1, 0
+	b.Range(func(x int) bool {
+		if x <= prev {
+			t.Fatalf("TestPages Range failed. Expected increasing, Got %d after %d", x, prev)
+		}
+		prev = x
+		count++
+		return true
+	})
+	if count != 20001 || b.Len() != 20001 || !b.Has(1<<23+1) || !b.Copy().Has(1<<23+1) {
+		t.Errorf("TestPages Add failed. Expected 20001, Got %d", count)
+	}
+}
+
 func BenchmarkBitmap(b *testing.B) {
 	bm := bitmap.New()
 	const memory = 100000000
@@ -148,6 +215,40 @@ func BenchmarkBitmap(b *testing.B) {
 	}
 }
 
+func BenchmarkAdd(b *testing.B) {
+	bm := bitmap.New()
+	const memory = 1 << 20
+	for i := 0; i < b.N; i++ {
+		bm.Add(i * 7 % memory)
+	}
+}
+
+func BenchmarkHas(b *testing.B) {
+	bm := bitmap.New()
+	const memory = 1 << 20
+	for i := 0; i < memory; i += 3 {
+		bm.Add(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bm.Has(i * 7 % memory)
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	x, y := bitmap.New(), bitmap.New()
+	const memory = 1 << 20
+	for i := 0; i < memory; i += 3 {
+		x.Add(i)
+		y.Add(i + 1)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bm := x.Copy()
+		bm.Union(y)
+	}
+}
+
 func TestCAdd(t *testing.T) {
 	b := bitmap.NewC(3)
 	b.Add(-1)
@@ -271,6 +372,73 @@ func TestCount(t *testing.T) {
 	}
 }
 
+func TestCDecay(t *testing.T) {
+	b := bitmap.NewC(7)
+	for i := 0; i < 7; i++ {
+		b.Add(0)
+	}
+	b.Add(1)
+	b.Add(2)
+	b.Add(2)
+	b.Add(2)
+	b.Add(10000)
+	b.Add(10000)
+	b.Halve()
+	if b.Count(0) != 3 || b.Count(1) != 0 || b.Count(2) != 1 || b.Count(10000) != 1 {
+		t.Errorf("TestCDecay Halve failed. Got %d %d %d %d", b.Count(0), b.Count(1), b.Count(2), b.Count(10000))
+	}
+	if b.Len() != 3 || b.Has(1) {
+		t.Errorf("TestCDecay Len failed. Expected 3, Got %d", b.Len())
+	}
+	b.Decay(2)
+	if b.Len() != 0 || b.String() != "{}" {
+		t.Errorf("TestCDecay Decay failed. Expected {}, Got %s", b.String())
+	}
+}
+
+func TestCPeriod(t *testing.T) {
+	b := bitmap.NewC(15)
+	b.SetPeriod(10)
+	for i := 0; i < 9; i++ {
+		b.Add(3)
+	}
+	if b.Count(3) != 9 {
+		t.Errorf("TestCPeriod failed. Expected 9, Got %d", b.Count(3))
+	}
+	b.Add(4)
+	if b.Count(3) != 4 || b.Count(4) != 0 || b.Len() != 1 {
+		t.Errorf("TestCPeriod failed. Expected 4, Got %d", b.Count(3))
+	}
+	b.SetPeriod(0)
+	for i := 0; i < 10; i++ {
+		b.Add(3)
+	}
+	if b.Count(3) != 14 {
+		t.Errorf("TestCPeriod failed. Expected 14, Got %d", b.Count(3))
+	}
+}
+
+func TestCPortable(t *testing.T) {
+	// 21 counters of 3 bits in every word on all platforms, 4 words at first
+	c := bitmap.NewC(5)
+	c.Add(83)
+	before := c.MemoryUsage()
+	c.Add(84)
+	if before != 32 || c.MemoryUsage() != 64 {
+		t.Errorf("TestCPortable failed. Expected 32 64 bytes, Got %d %d", before, c.MemoryUsage())
+	}
+	// the largest n is the largest int on 32 bits platforms
+	if c = bitmap.NewC(math.MaxInt); c == nil || bitmap.NewC(0) != nil {
+		t.Fatalf("TestCPortable failed. Expected counter to %d", math.MaxInt)
+	}
+	for i := 0; i < 3; i++ {
+		c.Add(7)
+	}
+	if c.Count(7) != 3 {
+		t.Errorf("TestCPortable failed. Expected counter to %d", math.MaxInt)
+	}
+}
+
 func BenchmarkCBitmap(b *testing.B) {
 	bm := bitmap.NewC(3)
 	const memory = 100000000
@@ -377,6 +545,21 @@ func TestRCopy(t *testing.T) {
 	}
 }
 
+func TestRRange(t *testing.T) {
+	b := bitmap.NewR(-5, 100)
+	b.Add(99)
+	b.Add(-5)
+	b.Add(7)
+	var xs []int
+	b.Range(func(x int) bool {
+		xs = append(xs, x)
+		return true
+	})
+	if len(xs) != 3 || xs[0] != -5 || xs[1] != 7 || xs[2] != 99 {
+		t.Errorf("TestRRange failed. Expected [-5 7 99], Got %v", xs)
+	}
+}
+
 func TestRSets(t *testing.T) {
 	b := bitmap.NewR(-1, 10001)
 	b.Add(-1)
@@ -412,6 +595,9 @@ func TestRSets(t *testing.T) {
 	if bb.String() != "{-1 0 1 2 5 6 10000}" {
 		t.Errorf("TestRSets SymExcept failed. Expected {-1 0 1 2 5 6 10000}, Got %s", bb.String())
 	}
+	if bb.Len() != 7 {
+		t.Errorf("TestRSets SymExcept Len failed. Expected 7, Got %d", bb.Len())
+	}
 }
 
 func BenchmarkRBitmap(b *testing.B) {
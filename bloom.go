@@ -0,0 +1,191 @@
+package bitmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// bloomMaxHashes is the largest number of hash functions of a filter
+// bloomMaxBits is the largest number of bits of a filter, NewBloom and UnmarshalBinary
+// both keep it, so every filter can be encoded and decoded on 32 and 64 bits platforms
+const (
+	bloomMaxHashes = 256
+	bloomMaxBits   = math.MaxInt32
+)
+
+// BloomFilter is a probabilistic set of byte strings backed by a NBitmap
+// Test may return true for data never added, but never false for data added
+type BloomFilter struct {
+	m    int // number of bits
+	k    int // number of hash functions
+	bits *NBitmap
+}
+
+// NewBloom return a new bloom filter for n expected items with false positive rate p
+// return nil if the filter needs more than 1<<31-1 bits
+func NewBloom(n int, p float64) *BloomFilter {
+	if n <= 0 || p <= 0 || p >= 1 {
+		return nil
+	}
+	m, k := bloomSize(n, p)
+	if m == 0 {
+		return nil
+	}
+	return newBloom(m, k)
+}
+
+func newBloom(m int, k int) *BloomFilter {
+	return &BloomFilter{
+		m:    m,
+		k:    k,
+		bits: New(),
+	}
+}
+
+// Size return number of bits in filter
+func (f *BloomFilter) Size() int {
+	return f.m
+}
+
+// Hashes return number of hash functions used by filter
+func (f *BloomFilter) Hashes() int {
+	return f.k
+}
+
+// AddBytes add data to the filter
+func (f *BloomFilter) AddBytes(data []byte) {
+	h1, h2 := bloomHashes(data)
+	for i := 0; i < f.k; i++ {
+		f.bits.Add(int((h1 + uint64(i)*h2) % uint64(f.m)))
+	}
+}
+
+// AddString add s to the filter
+func (f *BloomFilter) AddString(s string) {
+	f.AddBytes([]byte(s))
+}
+
+// Test return true if data may be in the filter
+func (f *BloomFilter) Test(data []byte) bool {
+	h1, h2 := bloomHashes(data)
+	for i := 0; i < f.k; i++ {
+		if !f.bits.Has(int((h1 + uint64(i)*h2) % uint64(f.m))) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestString return true if s may be in the filter
+func (f *BloomFilter) TestString(s string) bool {
+	return f.Test([]byte(s))
+}
+
+// FillRatio return the ratio of bits set in filter
+func (f *BloomFilter) FillRatio() float64 {
+	return float64(f.bits.Len()) / float64(f.m)
+}
+
+// EstimateCount return the estimated number of items added
+func (f *BloomFilter) EstimateCount() int {
+	fill := f.FillRatio()
+	if fill >= 1 {
+		return math.MaxInt32
+	}
+	return int(math.Round(-float64(f.m) / float64(f.k) * math.Log(1-fill)))
+}
+
+// Union f = f | c
+// data in f or c
+// f must have the same size and hashes of c
+func (f *BloomFilter) Union(c *BloomFilter) {
+	if f.m != c.m || f.k != c.k {
+		return
+	}
+	f.bits.Union(c.bits)
+}
+
+// Intersect f = f & c
+// data both in f and c, with a higher false positive rate
+// f must have the same size and hashes of c
+func (f *BloomFilter) Intersect(c *BloomFilter) {
+	if f.m != c.m || f.k != c.k {
+		return
+	}
+	f.bits.Intersect(c.bits)
+}
+
+// Clear make the filter empty
+func (f *BloomFilter) Clear() {
+	*f = *newBloom(f.m, f.k)
+}
+
+// Copy return a copy filter
+func (f *BloomFilter) Copy() *BloomFilter {
+	return &BloomFilter{
+		m:    f.m,
+		k:    f.k,
+		bits: f.bits.Copy(),
+	}
+}
+
+// MarshalBinary encode the filter as
+// size and hashes as little endian uint64, then the bits as little endian uint64s
+func (f *BloomFilter) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 16, 16+(f.m-1)/64*8+8)
+	binary.LittleEndian.PutUint64(b, uint64(f.m))
+	binary.LittleEndian.PutUint64(b[8:], uint64(f.k))
+	return appendWords(b, f.bits.words((f.m-1)/bitSize+1)), nil
+}
+
+// UnmarshalBinary decode data encoded by MarshalBinary into the filter
+func (f *BloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return errShortBuffer
+	}
+	m := binary.LittleEndian.Uint64(data)
+	k := binary.LittleEndian.Uint64(data[8:])
+	if m == 0 || k == 0 || m > bloomMaxBits || k > bloomMaxHashes {
+		return errors.New("bitmap: invalid bloom filter header")
+	}
+	words, err := readWords(data[16:], int((m+63)/64))
+	if err != nil {
+		return err
+	}
+	words = words[:int((m+bitSize-1)/bitSize)]
+	if m%bitSize != 0 && words[len(words)-1]>>(m%bitSize) != 0 {
+		return errors.New("bitmap: bloom filter bits out of size")
+	}
+	*f = BloomFilter{
+		m:    int(m),
+		k:    int(k),
+		bits: newFromWords(words),
+	}
+	return nil
+}
+
+// bloomSize return the optimal bits and hashes for n items with false positive rate p
+// m is 0 if it's more than bloomMaxBits
+func bloomSize(n int, p float64) (int, int) {
+	bits := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	if bits > bloomMaxBits {
+		return 0, 0
+	}
+	m := int(bits)
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > bloomMaxHashes {
+		k = bloomMaxHashes
+	}
+	return m, k
+}
+
+// bloomHashes return two hashes of data, the i-th hash is h1 + i*h2
+func bloomHashes(data []byte) (uint64, uint64) {
+	h1 := hashBytes(data)
+	h2 := mix64(h1) | 1
+	return h1, h2
+}
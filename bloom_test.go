@@ -0,0 +1,130 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestBloomAdd(t *testing.T) {
+	f := bitmap.NewBloom(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.AddString(strconv.Itoa(i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.TestString(strconv.Itoa(i)) {
+			t.Fatalf("TestBloomAdd failed. Expected %d in filter", i)
+		}
+	}
+	fp := 0
+	for i := 1000; i < 11000; i++ {
+		if f.Test([]byte(strconv.Itoa(i))) {
+			fp++
+		}
+	}
+	if fp > 200 {
+		t.Errorf("TestBloomAdd failed. Expected about 100 false positives, Got %d", fp)
+	}
+	if f.FillRatio() < 0.4 || f.FillRatio() > 0.6 {
+		t.Errorf("TestBloomAdd failed. Expected fill ratio about 0.5, Got %f", f.FillRatio())
+	}
+	if n := f.EstimateCount(); n < 900 || n > 1100 {
+		t.Errorf("TestBloomAdd failed. Expected about 1000 items, Got %d", n)
+	}
+}
+
+func TestBloomNew(t *testing.T) {
+	if bitmap.NewBloom(0, 0.01) != nil || bitmap.NewBloom(10, 0) != nil || bitmap.NewBloom(10, 1) != nil {
+		t.Errorf("TestBloomNew failed. Expected nil")
+	}
+	f := bitmap.NewBloom(1000, 0.01)
+	if f.Size() != 9586 || f.Hashes() != 7 {
+		t.Errorf("TestBloomNew failed. Expected 9586 bits 7 hashes, Got %d %d", f.Size(), f.Hashes())
+	}
+	// filters larger than 1<<31-1 bits can not be decoded, so they are not built
+	if bitmap.NewBloom(300000000, 0.01) != nil || bitmap.NewCountingBloom(300000000, 0.01, 15) != nil {
+		t.Errorf("TestBloomNew failed. Expected nil for more than 1<<31-1 bits")
+	}
+	if f := bitmap.NewBloom(200000000, 0.01); f == nil || f.Size() != 1917011676 {
+		t.Errorf("TestBloomNew failed. Expected 1917011676 bits, Got %v", f)
+	}
+}
+
+func TestBloomSets(t *testing.T) {
+	f := bitmap.NewBloom(100, 0.01)
+	g := bitmap.NewBloom(100, 0.01)
+	f.AddString("a")
+	f.AddString("b")
+	g.AddString("b")
+	g.AddString("c")
+	u := f.Copy()
+	u.Union(g)
+	if !u.TestString("a") || !u.TestString("b") || !u.TestString("c") {
+		t.Errorf("TestBloomSets Union failed.")
+	}
+	i := f.Copy()
+	i.Intersect(g)
+	if !i.TestString("b") || i.TestString("a") || i.TestString("c") {
+		t.Errorf("TestBloomSets Intersect failed.")
+	}
+	h := bitmap.NewBloom(200, 0.01)
+	h.Union(f)
+	if h.TestString("a") {
+		t.Errorf("TestBloomSets Union of different filters failed.")
+	}
+	f.Clear()
+	if f.TestString("a") || f.FillRatio() != 0 {
+		t.Errorf("TestBloomSets Clear failed.")
+	}
+}
+
+func TestBloomBinary(t *testing.T) {
+	f := bitmap.NewBloom(100, 0.01)
+	f.AddString("a")
+	f.AddString("b")
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("TestBloomBinary failed. %v", err)
+	}
+	g := &bitmap.BloomFilter{}
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatalf("TestBloomBinary failed. %v", err)
+	}
+	if !g.TestString("a") || !g.TestString("b") || g.FillRatio() != f.FillRatio() || g.Size() != f.Size() {
+		t.Errorf("TestBloomBinary failed.")
+	}
+	if err := g.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("TestBloomBinary failed. Expected error for short data")
+	}
+	bad := append([]byte(nil), data...)
+	bad[len(bad)-1] |= 0x80 // bit 1023 is out of 959 bits
+	if err := g.UnmarshalBinary(bad); err == nil {
+		t.Errorf("TestBloomBinary failed. Expected error for bits out of size")
+	}
+	bad = append([]byte(nil), data...)
+	bad[12] = 1 // 1<<32 hashes
+	if err := g.UnmarshalBinary(bad); err == nil {
+		t.Errorf("TestBloomBinary failed. Expected error for too many hashes")
+	}
+}
+
+func TestBloomPortable(t *testing.T) {
+	// encoded bytes are the same on 32 and 64 bits platforms
+	f := bitmap.NewBloom(10, 0.1)
+	f.AddString("a")
+	data, _ := f.MarshalBinary()
+	expected := "300000000000000003000000000000000400002000010000"
+	if s := fmt.Sprintf("%x", data); s != expected {
+		t.Errorf("TestBloomPortable failed. Expected %s, Got %s", expected, s)
+	}
+}
+
+func BenchmarkBloom(b *testing.B) {
+	f := bitmap.NewBloom(1000000, 0.01)
+	data := []byte("bitmap")
+	for i := 0; i < b.N; i++ {
+		f.AddBytes(data)
+		f.Test(data)
+	}
+}
@@ -0,0 +1,127 @@
+package bitmap
+
+// CountingBloomFilter is a bloom filter whose cells are CBitmap counters
+// so data can be removed from it
+type CountingBloomFilter struct {
+	m     int // number of cells
+	k     int // number of hash functions
+	cells *CBitmap
+}
+
+// NewCountingBloom return a new counting bloom filter
+// for n expected items with false positive rate p, every cell can count to max
+// return nil if the filter needs more than 1<<31-1 cells
+func NewCountingBloom(n int, p float64, max int) *CountingBloomFilter {
+	if n <= 0 || p <= 0 || p >= 1 {
+		return nil
+	}
+	m, k := bloomSize(n, p)
+	cells := NewC(max)
+	if m == 0 || cells == nil {
+		return nil
+	}
+	return &CountingBloomFilter{
+		m:     m,
+		k:     k,
+		cells: cells,
+	}
+}
+
+// Size return number of cells in filter
+func (f *CountingBloomFilter) Size() int {
+	return f.m
+}
+
+// Hashes return number of hash functions used by filter
+func (f *CountingBloomFilter) Hashes() int {
+	return f.k
+}
+
+// Add add data to the filter
+// cells stop counting at max instead of overflow
+func (f *CountingBloomFilter) Add(data []byte) {
+	h1, h2 := bloomHashes(data)
+	for i := 0; i < f.k; i++ {
+		f.cells.Add(int((h1 + uint64(i)*h2) % uint64(f.m)))
+	}
+}
+
+// Remove remove data from the filter
+// nothing happens if data is not in the filter, saturated cells are kept
+// because their real counts are unknown
+func (f *CountingBloomFilter) Remove(data []byte) {
+	if !f.Test(data) {
+		return
+	}
+	h1, h2 := bloomHashes(data)
+	for i := 0; i < f.k; i++ {
+		x := int((h1 + uint64(i)*h2) % uint64(f.m))
+		if f.cells.Count(x) < int(f.cells.n) {
+			f.cells.Remove(x)
+		}
+	}
+}
+
+// Test return true if data may be in the filter
+func (f *CountingBloomFilter) Test(data []byte) bool {
+	return f.EstimateCount(data) > 0
+}
+
+// EstimateCount return the estimated times data was added
+// the estimate is never less than the real count unless cells are saturated
+func (f *CountingBloomFilter) EstimateCount(data []byte) int {
+	h1, h2 := bloomHashes(data)
+	min := int(f.cells.n)
+	for i := 0; i < f.k; i++ {
+		count := f.cells.Count(int((h1 + uint64(i)*h2) % uint64(f.m)))
+		if count < min {
+			min = count
+		}
+	}
+	return min
+}
+
+// Merge f = f + c, add every cell of c to f
+// cells stop counting at max instead of overflow
+// f must have the same size, hashes and max of c
+func (f *CountingBloomFilter) Merge(c *CountingBloomFilter) {
+	if f.m != c.m || f.k != c.k || f.cells.n != c.cells.n {
+		return
+	}
+	c.cells.Range(func(x int, count int) bool {
+		f.cells.addCount(x, count)
+		return true
+	})
+}
+
+// addCount add count to the counter of x, saturating at n
+// the memory budget is ignored
+func (c *CBitmap) addCount(x int, count int) {
+	word, bit := x/c.bitSize, bitInt(x%c.bitSize*c.numSize)
+	if word >= len(c.words) {
+		c.words = growWords(c.words, word+1, 0)
+	}
+	old := (c.words[word] >> bit) & c.mask
+	if old == 0 {
+		c.len++
+	}
+	sum := old + bitInt(count)
+	if sum > c.n {
+		sum = c.n
+	}
+	c.words[word] += (sum - old) << bit
+}
+
+// Clear make the filter empty
+func (f *CountingBloomFilter) Clear() {
+	f.cells.Clear()
+}
+
+// Copy return a copy filter
+func (f *CountingBloomFilter) Copy() *CountingBloomFilter {
+	return &CountingBloomFilter{
+		m:     f.m,
+		k:     f.k,
+		cells: f.cells.Copy(),
+	}
+}
@@ -0,0 +1,92 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"strconv"
+	"testing"
+)
+
+func TestCountingBloomAdd(t *testing.T) {
+	f := bitmap.NewCountingBloom(1000, 0.01, 15)
+	for i := 0; i < 1000; i++ {
+		f.Add([]byte(strconv.Itoa(i)))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.Test([]byte(strconv.Itoa(i))) {
+			t.Fatalf("TestCountingBloomAdd failed. Expected %d in filter", i)
+		}
+	}
+	fp := 0
+	for i := 1000; i < 11000; i++ {
+		if f.Test([]byte(strconv.Itoa(i))) {
+			fp++
+		}
+	}
+	if fp > 200 {
+		t.Errorf("TestCountingBloomAdd failed. Expected about 100 false positives, Got %d", fp)
+	}
+}
+
+func TestCountingBloomRemove(t *testing.T) {
+	f := bitmap.NewCountingBloom(100, 0.01, 3)
+	a, b := []byte("a"), []byte("b")
+	f.Add(a)
+	f.Add(a)
+	f.Add(b)
+	if f.EstimateCount(a) != 2 || f.EstimateCount(b) != 1 {
+		t.Errorf("TestCountingBloomRemove failed. Expected 2 1, Got %d %d", f.EstimateCount(a), f.EstimateCount(b))
+	}
+	f.Remove(a)
+	f.Remove(b)
+	if !f.Test(a) || f.Test(b) {
+		t.Errorf("TestCountingBloomRemove failed.")
+	}
+	f.Remove(a)
+	f.Remove(a)
+	if f.Test(a) {
+		t.Errorf("TestCountingBloomRemove failed. Expected a removed")
+	}
+	for i := 0; i < 10; i++ {
+		f.Add(b)
+	}
+	f.Remove(b)
+	if f.EstimateCount(b) != 3 {
+		t.Errorf("TestCountingBloomRemove saturate failed. Expected 3, Got %d", f.EstimateCount(b))
+	}
+}
+
+func TestCountingBloomMerge(t *testing.T) {
+	f := bitmap.NewCountingBloom(100, 0.01, 7)
+	g := bitmap.NewCountingBloom(100, 0.01, 7)
+	a, b := []byte("a"), []byte("b")
+	f.Add(a)
+	f.Add(b)
+	g.Add(b)
+	g.Add(b)
+	c := f.Copy()
+	c.Merge(g)
+	if c.EstimateCount(a) != 1 || c.EstimateCount(b) != 3 || f.EstimateCount(b) != 1 {
+		t.Errorf("TestCountingBloomMerge failed. Expected 1 3, Got %d %d", c.EstimateCount(a), c.EstimateCount(b))
+	}
+	for i := 0; i < 5; i++ {
+		g.Add(a)
+	}
+	c.Merge(g)
+	if c.EstimateCount(a) != 6 || c.EstimateCount(b) != 5 {
+		t.Errorf("TestCountingBloomMerge failed. Expected 6 5, Got %d %d", c.EstimateCount(a), c.EstimateCount(b))
+	}
+	c.Merge(g)
+	if c.EstimateCount(a) != 7 || c.EstimateCount(b) != 7 {
+		t.Errorf("TestCountingBloomMerge saturate failed. Expected 7 7, Got %d %d", c.EstimateCount(a), c.EstimateCount(b))
+	}
+	h := bitmap.NewCountingBloom(100, 0.01, 15)
+	h.Add(a)
+	h.Merge(g)
+	if h.EstimateCount(a) != 1 {
+		t.Errorf("TestCountingBloomMerge failed. Expected different max rejected, Got %d", h.EstimateCount(a))
+	}
+	c.Clear()
+	if c.Test(a) || c.Test(b) {
+		t.Errorf("TestCountingBloomMerge Clear failed.")
+	}
+}
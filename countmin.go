@@ -0,0 +1,282 @@
+package bitmap
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// CountMinSketch estimate frequencies of keys in a stream
+// every row is a PackedArray of counters, estimates are never less than
+// the real counts unless counters are saturated
+type CountMinSketch struct {
+	width        int
+	depth        int
+	max          uint64
+	conservative bool
+	rows         []*PackedArray
+	top          int                // number of heavy hitters tracked, 0 to disable
+	hitters      map[string]*hitter // heavy hitter candidates by key
+	heap         hitterHeap         // heavy hitter candidates, smallest estimate first
+}
+
+// HeavyHitter is a key with its estimated count
+type HeavyHitter struct {
+	Key   string
+	Count int
+}
+
+// NewCountMin return a new sketch of depth rows with width counters
+// every counter use bits bits so it can count to 2^bits - 1
+func NewCountMin(width int, depth int, bits int) *CountMinSketch {
+	if width <= 0 || depth <= 0 || bits <= 0 || bits >= bitSize {
+		return nil
+	}
+	s := CountMinSketch{}
+	s.width = width
+	s.depth = depth
+	s.max = 1<<uint(bits) - 1
+	s.rows = make([]*PackedArray, depth)
+	for i := range s.rows {
+		s.rows[i] = NewPacked(bits, width)
+	}
+	return &s
+}
+
+// Width return number of counters in every row
+func (s *CountMinSketch) Width() int {
+	return s.width
+}
+
+// Depth return number of rows
+func (s *CountMinSketch) Depth() int {
+	return s.depth
+}
+
+// SetConservative enable or disable conservative update
+// with conservative update Add only raise counters below the new estimate,
+// it reduces overestimation but sketches can not be merged exactly
+func (s *CountMinSketch) SetConservative(conservative bool) {
+	s.conservative = conservative
+}
+
+// TrackHeavyHitters make the sketch keep the k keys with the largest estimates
+// k <= 0 disable the tracking
+func (s *CountMinSketch) TrackHeavyHitters(k int) {
+	if k <= 0 {
+		s.top = 0
+		s.hitters, s.heap = nil, nil
+		return
+	}
+	s.top = k
+	if s.hitters == nil {
+		s.hitters = make(map[string]*hitter)
+	}
+	s.trimHitters()
+}
+
+// Add add n occurrences of key
+func (s *CountMinSketch) Add(key []byte, n int) {
+	if n <= 0 {
+		return
+	}
+	h1, h2 := bloomHashes(key)
+	if s.conservative {
+		target := s.saturate(uint64(s.estimate(h1, h2)) + uint64(n))
+		for i, row := range s.rows {
+			x := s.index(h1, h2, i)
+			if row.Get(x) < target {
+				row.Set(x, target)
+			}
+		}
+	} else {
+		for i, row := range s.rows {
+			x := s.index(h1, h2, i)
+			row.Set(x, s.saturate(row.Get(x)+uint64(n)))
+		}
+	}
+	if s.top > 0 {
+		s.offer(string(key), s.estimate(h1, h2))
+	}
+}
+
+// AddString add n occurrences of key
+func (s *CountMinSketch) AddString(key string, n int) {
+	s.Add([]byte(key), n)
+}
+
+// Estimate return the estimated count of key
+func (s *CountMinSketch) Estimate(key []byte) int {
+	h1, h2 := bloomHashes(key)
+	return s.estimate(h1, h2)
+}
+
+// EstimateString return the estimated count of key
+func (s *CountMinSketch) EstimateString(key string) int {
+	return s.Estimate([]byte(key))
+}
+
+// HeavyHitters return tracked keys ordered by estimated count, largest first
+func (s *CountMinSketch) HeavyHitters() []HeavyHitter {
+	hitters := make([]HeavyHitter, 0, len(s.heap))
+	for _, h := range s.heap {
+		hitters = append(hitters, HeavyHitter{Key: h.key, Count: h.count})
+	}
+	sort.Slice(hitters, func(i, j int) bool {
+		if hitters[i].Count != hitters[j].Count {
+			return hitters[i].Count > hitters[j].Count
+		}
+		return hitters[i].Key < hitters[j].Key
+	})
+	return hitters
+}
+
+// Merge s = s + c, add every counter of c to s
+// s must have the same width and depth of c
+func (s *CountMinSketch) Merge(c *CountMinSketch) {
+	if s.width != c.width || s.depth != c.depth {
+		return
+	}
+	for i, row := range s.rows {
+		values := c.rows[i].Uint64s(nil)
+		for x, v := range values {
+			if v != 0 {
+				row.Set(x, s.saturate(row.Get(x)+v))
+			}
+		}
+	}
+	if s.top > 0 {
+		for _, h := range s.heap {
+			h.count = s.EstimateString(h.key)
+		}
+		heap.Init(&s.heap)
+		for key := range c.hitters {
+			s.offer(key, s.EstimateString(key))
+		}
+	}
+}
+
+// Clear make the sketch empty
+func (s *CountMinSketch) Clear() {
+	for _, row := range s.rows {
+		row.Fill(0, row.Len(), 0)
+	}
+	if s.hitters != nil {
+		s.hitters = make(map[string]*hitter)
+		s.heap = nil
+	}
+}
+
+// Copy return a copy sketch
+func (s *CountMinSketch) Copy() *CountMinSketch {
+	new := *s
+	new.rows = make([]*PackedArray, len(s.rows))
+	for i, row := range s.rows {
+		new.rows[i] = row.Copy()
+	}
+	if s.hitters != nil {
+		new.hitters = make(map[string]*hitter, len(s.hitters))
+		new.heap = make(hitterHeap, len(s.heap))
+		for i, h := range s.heap {
+			c := *h
+			new.heap[i] = &c
+			new.hitters[c.key] = &c
+		}
+	}
+	return &new
+}
+
+// index return the counter of key in row i
+func (s *CountMinSketch) index(h1 uint64, h2 uint64, i int) int {
+	return int((h1 + uint64(i)*h2) % uint64(s.width))
+}
+
+// estimate return the smallest counter of key
+func (s *CountMinSketch) estimate(h1 uint64, h2 uint64) int {
+	min := s.max
+	for i, row := range s.rows {
+		if v := row.Get(s.index(h1, h2, i)); v < min {
+			min = v
+		}
+	}
+	return int(min)
+}
+
+// saturate return v or the max count if v is larger
+func (s *CountMinSketch) saturate(v uint64) uint64 {
+	if v > s.max {
+		return s.max
+	}
+	return v
+}
+
+// offer update the estimate of key in heavy hitters
+// the smallest heavy hitter is replaced if key is not tracked and count is larger
+func (s *CountMinSketch) offer(key string, count int) {
+	if h, ok := s.hitters[key]; ok {
+		h.count = count
+		heap.Fix(&s.heap, h.index)
+		return
+	}
+	if len(s.heap) < s.top {
+		h := &hitter{key: key, count: count}
+		heap.Push(&s.heap, h)
+		s.hitters[key] = h
+		return
+	}
+	if min := s.heap[0]; count > min.count {
+		delete(s.hitters, min.key)
+		min.key, min.count = key, count
+		s.hitters[key] = min
+		heap.Fix(&s.heap, 0)
+	}
+}
+
+// trimHitters drop the smallest heavy hitters until at most top are kept
+func (s *CountMinSketch) trimHitters() {
+	for len(s.heap) > s.top {
+		h := heap.Pop(&s.heap).(*hitter)
+		delete(s.hitters, h.key)
+	}
+}
+
+// hitter is a heavy hitter candidate in hitterHeap
+type hitter struct {
+	key   string
+	count int
+	index int // index in hitterHeap
+}
+
+// hitterHeap is a min heap of heavy hitters by count,
+// the larger key is smaller for the same count, it's the reverse of HeavyHitters
+type hitterHeap []*hitter
+
+func (h hitterHeap) Len() int {
+	return len(h)
+}
+
+func (h hitterHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].key > h[j].key
+}
+
+func (h hitterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hitterHeap) Push(x interface{}) {
+	e := x.(*hitter)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *hitterHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,123 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"strconv"
+	"testing"
+)
+
+func TestCountMinAdd(t *testing.T) {
+	s := bitmap.NewCountMin(1000, 4, 16)
+	for i := 0; i < 500; i++ {
+		s.AddString(strconv.Itoa(i), i%10+1)
+	}
+	s.Add([]byte("big"), 1000)
+	for i := 0; i < 500; i++ {
+		if s.EstimateString(strconv.Itoa(i)) < i%10+1 {
+			t.Fatalf("TestCountMinAdd failed. Expected at least %d, Got %d", i%10+1, s.EstimateString(strconv.Itoa(i)))
+		}
+	}
+	if s.Estimate([]byte("big")) < 1000 || s.Estimate([]byte("big")) > 1020 {
+		t.Errorf("TestCountMinAdd failed. Expected about 1000, Got %d", s.Estimate([]byte("big")))
+	}
+	if s.Width() != 1000 || s.Depth() != 4 {
+		t.Errorf("TestCountMinAdd failed. Expected 1000 4, Got %d %d", s.Width(), s.Depth())
+	}
+}
+
+func TestCountMinSaturate(t *testing.T) {
+	s := bitmap.NewCountMin(10, 2, 4)
+	s.AddString("a", 10)
+	s.AddString("a", 10)
+	if s.EstimateString("a") != 15 {
+		t.Errorf("TestCountMinSaturate failed. Expected 15, Got %d", s.EstimateString("a"))
+	}
+}
+
+func TestCountMinConservative(t *testing.T) {
+	plain := bitmap.NewCountMin(50, 3, 16)
+	cons := bitmap.NewCountMin(50, 3, 16)
+	cons.SetConservative(true)
+	for i := 0; i < 1000; i++ {
+		plain.AddString(strconv.Itoa(i%200), 1)
+		cons.AddString(strconv.Itoa(i%200), 1)
+	}
+	plainErr, consErr := 0, 0
+	for i := 0; i < 200; i++ {
+		p, c := plain.EstimateString(strconv.Itoa(i)), cons.EstimateString(strconv.Itoa(i))
+		if p < 5 || c < 5 {
+			t.Fatalf("TestCountMinConservative failed. Expected at least 5, Got %d %d", p, c)
+		}
+		plainErr += p - 5
+		consErr += c - 5
+	}
+	if consErr > plainErr {
+		t.Errorf("TestCountMinConservative failed. Expected error %d <= %d", consErr, plainErr)
+	}
+}
+
+func TestCountMinMerge(t *testing.T) {
+	s := bitmap.NewCountMin(100, 3, 16)
+	c := bitmap.NewCountMin(100, 3, 16)
+	s.AddString("a", 3)
+	c.AddString("a", 4)
+	c.AddString("b", 1)
+	m := s.Copy()
+	m.Merge(c)
+	if m.EstimateString("a") != 7 || m.EstimateString("b") != 1 || s.EstimateString("a") != 3 {
+		t.Errorf("TestCountMinMerge failed. Expected 7 1, Got %d %d", m.EstimateString("a"), m.EstimateString("b"))
+	}
+	m.Clear()
+	if m.EstimateString("a") != 0 {
+		t.Errorf("TestCountMinMerge Clear failed.")
+	}
+}
+
+func TestCountMinHeavyHitters(t *testing.T) {
+	s := bitmap.NewCountMin(1000, 4, 16)
+	s.TrackHeavyHitters(3)
+	for i := 0; i < 100; i++ {
+		s.AddString(strconv.Itoa(i), 1)
+	}
+	s.AddString("x", 50)
+	s.AddString("y", 40)
+	s.AddString("z", 30)
+	s.AddString("1", 1)
+	hitters := s.HeavyHitters()
+	if len(hitters) != 3 || hitters[0].Key != "x" || hitters[1].Key != "y" || hitters[2].Key != "z" ||
+		hitters[0].Count < 50 {
+		t.Errorf("TestCountMinHeavyHitters failed. Expected x y z, Got %v", hitters)
+	}
+	s.TrackHeavyHitters(1)
+	if hitters := s.HeavyHitters(); len(hitters) != 1 || hitters[0].Key != "x" {
+		t.Errorf("TestCountMinHeavyHitters failed. Expected x, Got %v", hitters)
+	}
+	c := bitmap.NewCountMin(1000, 4, 16)
+	c.TrackHeavyHitters(1)
+	c.AddString("w", 100)
+	s.Merge(c)
+	if hitters := s.HeavyHitters(); len(hitters) != 1 || hitters[0].Key != "w" {
+		t.Errorf("TestCountMinHeavyHitters Merge failed. Expected w, Got %v", hitters)
+	}
+	s.TrackHeavyHitters(0)
+	if hitters := s.HeavyHitters(); len(hitters) != 0 {
+		t.Errorf("TestCountMinHeavyHitters disable failed. Expected none, Got %v", hitters)
+	}
+	// tracking starts again from the keys added after
+	s.TrackHeavyHitters(2)
+	s.AddString("w", 1)
+	s.AddString("x", 1)
+	s.AddString("w", 1)
+	if hitters := s.HeavyHitters(); len(hitters) != 2 || hitters[0].Key != "w" || hitters[1].Key != "x" {
+		t.Errorf("TestCountMinHeavyHitters enable failed. Expected w x, Got %v", hitters)
+	}
+}
+
+func BenchmarkCountMin(b *testing.B) {
+	s := bitmap.NewCountMin(10000, 4, 16)
+	key := []byte("bitmap")
+	for i := 0; i < b.N; i++ {
+		s.Add(key, 1)
+		s.Estimate(key)
+	}
+}
@@ -0,0 +1,172 @@
+package bitmap
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// DCBitmap is a counting bitSet with dense packing
+// counters have exactly width bits and may straddle two words
+type DCBitmap struct {
+	len   int
+	n     bitInt
+	width int
+	words []bitInt
+}
+
+// NewDC return a new bitmap, every counter use width bits
+// so it can count to 2^width - 1
+func NewDC(width int) *DCBitmap {
+	if width <= 0 || width >= bitSize {
+		return nil
+	}
+	return &DCBitmap{
+		len:   0,
+		n:     (1 << bitInt(width)) - 1,
+		width: width,
+		words: make([]bitInt, bitmapSize),
+	}
+}
+
+// String return formated string of bitmap
+func (d *DCBitmap) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	fields := len(d.words) * bitSize / d.width
+	for x := 0; x < fields; x++ {
+		if getField(d.words, x*d.width, d.width) != 0 {
+			if buf.Len() > len("{") {
+				buf.WriteByte(' ')
+			}
+			fmt.Fprintf(&buf, "%d", x)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers in bitmap
+func (d *DCBitmap) Len() int {
+	return d.len
+}
+
+// Width return bits used by every counter
+func (d *DCBitmap) Width() int {
+	return d.width
+}
+
+// Has return true if x is in the bitmap
+func (d *DCBitmap) Has(x int) bool {
+	return d.Count(x) != 0
+}
+
+// pos return the first bit of the counter of x, false if x is negative
+// or its counter is past the largest int
+func (d *DCBitmap) pos(x int) (int, bool) {
+	if x < 0 || x > (math.MaxInt-d.width)/d.width {
+		return 0, false
+	}
+	return x * d.width, true
+}
+
+// Add add x to the bitmap
+// x is ignored if it's negative or x*width overflows int
+func (d *DCBitmap) Add(x int) {
+	pos, ok := d.pos(x)
+	if !ok {
+		return
+	}
+	if word := (pos + d.width - 1) / bitSize; word >= len(d.words) {
+		d.words = append(d.words, make([]bitInt, word+1-len(d.words))...)
+	}
+	v := getField(d.words, pos, d.width)
+	if v == 0 {
+		d.len++
+	}
+	if v < d.n {
+		setField(d.words, pos, d.width, v+1)
+	}
+}
+
+// Remove remove x in bitmap
+func (d *DCBitmap) Remove(x int) {
+	pos, ok := d.pos(x)
+	if !ok {
+		return
+	}
+	if (pos+d.width-1)/bitSize < len(d.words) {
+		v := getField(d.words, pos, d.width)
+		if v != 0 {
+			setField(d.words, pos, d.width, v-1)
+			if v == 1 {
+				d.len--
+			}
+		}
+	}
+}
+
+// Count return the numSize of x elements
+func (d *DCBitmap) Count(x int) int {
+	pos, ok := d.pos(x)
+	if !ok {
+		return 0
+	}
+	if (pos+d.width-1)/bitSize >= len(d.words) {
+		return 0
+	}
+	return int(getField(d.words, pos, d.width))
+}
+
+// RemoveAll remove x in bitmap
+func (d *DCBitmap) RemoveAll(x int) {
+	pos, ok := d.pos(x)
+	if !ok {
+		return
+	}
+	if (pos+d.width-1)/bitSize < len(d.words) {
+		if getField(d.words, pos, d.width) != 0 {
+			d.len--
+			setField(d.words, pos, d.width, 0)
+		}
+	}
+}
+
+// Clear make the bitmap empty
+func (d *DCBitmap) Clear() {
+	*d = *NewDC(d.width)
+}
+
+// Copy return a copy bitmap
+func (d *DCBitmap) Copy() *DCBitmap {
+	new := DCBitmap{}
+	new.len = d.len
+	new.n = d.n
+	new.width = d.width
+	new.words = make([]bitInt, len(d.words))
+	copy(new.words, d.words)
+	return &new
+}
+
+// getField return the width bits value start at bit pos of words
+func getField(words []bitInt, pos int, width int) bitInt {
+	word, off := pos/bitSize, bitInt(pos%bitSize)
+	mask := bitInt(1)<<bitInt(width) - 1
+	v := words[word] >> off
+	if int(off)+width > bitSize {
+		v |= words[word+1] << (bitSize - off)
+	}
+	return v & mask
+}
+
+// setField set the width bits value start at bit pos of words to v
+func setField(words []bitInt, pos int, width int, v bitInt) {
+	word, off := pos/bitSize, bitInt(pos%bitSize)
+	mask := bitInt(1)<<bitInt(width) - 1
+	v &= mask
+	words[word] = words[word]&^(mask<<off) | v<<off
+	if int(off)+width > bitSize {
+		shift := bitSize - off
+		words[word+1] = words[word+1]&^(mask>>shift) | v>>shift
+	}
+}
@@ -0,0 +1,128 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"math"
+	"testing"
+)
+
+func TestDCAdd(t *testing.T) {
+	b := bitmap.NewDC(3)
+	b.Add(-1)
+	b.Add(0)
+	b.Add(1)
+	b.Add(2)
+	b.Add(10000)
+	if b.Len() != 4 || b.String() != "{0 1 2 10000}" {
+		t.Errorf("TestDCAdd failed. Expected {0 1 2 10000}, Got %s", b.String())
+	}
+}
+
+func TestDCHas(t *testing.T) {
+	b := bitmap.NewDC(3)
+	b.Add(-1)
+	b.Add(0)
+	b.Add(1)
+	b.Add(2)
+	b.Add(10000)
+	if b.Has(-1) || !b.Has(0) || !b.Has(1) || !b.Has(2) || b.Has(3) || !b.Has(10000) || b.Has(100000) {
+		t.Errorf("TestDCHas failed.")
+	}
+}
+
+func TestDCRemove(t *testing.T) {
+	b := bitmap.NewDC(3)
+	b.Add(-1)
+	b.Add(0)
+	b.Add(1)
+	b.Add(2)
+	b.Add(2)
+	b.Add(4)
+	b.Remove(3)
+	if b.String() != "{0 1 2 4}" {
+		t.Errorf("TestDCRemove failed. Expected {0 1 2 4}, Got %s", b.String())
+	}
+	b.Remove(4)
+	if b.Has(4) {
+		t.Errorf("TestDCRemove failed. Expected false, Got true")
+	}
+	b.Remove(-1)
+	b.Remove(1)
+	b.Remove(2)
+	b.Remove(2)
+	b.Remove(0)
+	if b.String() != "{}" || b.Len() != 0 {
+		t.Errorf("TestDCRemove failed. Expected {}, Got %s", b.String())
+	}
+}
+
+func TestDCClear(t *testing.T) {
+	b := bitmap.NewDC(3)
+	b.Add(0)
+	b.Add(10000)
+	b.Clear()
+	if b.Has(0) || b.Len() != 0 {
+		t.Errorf("TestDCClear failed")
+	}
+}
+
+func TestDCCopy(t *testing.T) {
+	b := bitmap.NewDC(3)
+	b.Add(0)
+	b.Add(1)
+	b.Add(2)
+	c := b.Copy()
+	b.Remove(2)
+	if !c.Has(0) || !c.Has(1) || !c.Has(2) {
+		t.Errorf("TestDCCopy failed.")
+	}
+}
+
+func TestDCCount(t *testing.T) {
+	// 3 bits fields straddle word boundaries on both 32 and 64 bits words
+	b := bitmap.NewDC(3)
+	for x := 0; x < 200; x++ {
+		for i := 0; i < x%9; i++ {
+			b.Add(x)
+		}
+	}
+	for x := 0; x < 200; x++ {
+		expected := x % 9
+		if expected > 7 {
+			expected = 7
+		}
+		if b.Count(x) != expected {
+			t.Fatalf("TestDCCount Add failed. Expected %d for %d, Got %d", expected, x, b.Count(x))
+		}
+	}
+	b.Remove(21)
+	if b.Count(21) != 2 || b.Count(20) != 2 || b.Count(22) != 4 {
+		t.Errorf("TestDCCount Remove failed.")
+	}
+	b.RemoveAll(21)
+	if b.Count(21) != 0 || b.Count(20) != 2 || b.Count(22) != 4 {
+		t.Errorf("TestDCCount RemoveAll failed.")
+	}
+}
+
+func BenchmarkDCBitmap(b *testing.B) {
+	bm := bitmap.NewDC(3)
+	const memory = 100000000
+	for i := 0; i < b.N; i++ {
+		bm.Add(i % memory)
+		bm.Has(i % memory)
+		bm.Remove(i % memory)
+	}
+}
+
+func TestDCLarge(t *testing.T) {
+	// x*width overflows int, x is ignored
+	b := bitmap.NewDC(3)
+	b.Add(math.MaxInt)
+	b.Add(math.MaxInt / 3)
+	b.Remove(math.MaxInt)
+	b.RemoveAll(math.MaxInt)
+	if b.Len() != 0 || b.Count(math.MaxInt) != 0 || b.Has(math.MaxInt/3) {
+		t.Errorf("TestDCLarge failed. Expected empty bitmap, Got %v", b)
+	}
+}
@@ -0,0 +1,261 @@
+package bitmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// efSample is the distance of sampled ones and zeros used by select
+const efSample = 256
+
+// EliasFano is a compact sorted sequence of non negative integers
+// every element use about 2 + log2(u/n) bits, u is the largest element
+type EliasFano struct {
+	n     int // number of elements
+	u     int // universe, the largest element + 1
+	l     int // bits of low parts
+	lows  *PackedArray
+	highs []bitInt // high parts in unary, the i-th one is at high(i) + i
+	ones  []int    // position of every efSample-th one in highs
+	zeros []int    // position of every efSample-th zero in highs
+}
+
+// NewEliasFano return a new EliasFano of elements in n
+// return nil if n has the largest int
+func NewEliasFano(n *NBitmap) *EliasFano {
+	xs := make([]int, 0, n.Len())
+	n.Range(func(x int) bool {
+		xs = append(xs, x)
+		return true
+	})
+	return NewEliasFanoInts(xs)
+}
+
+// NewEliasFanoInts return a new EliasFano of xs
+// xs must be non negative, less than the largest int and in non decreasing order,
+// or nil is returned
+func NewEliasFanoInts(xs []int) *EliasFano {
+	for i, x := range xs {
+		if x < 0 || x == math.MaxInt || (i > 0 && x < xs[i-1]) {
+			return nil
+		}
+	}
+	e := EliasFano{}
+	e.n = len(xs)
+	if e.n > 0 {
+		e.u = xs[e.n-1] + 1
+	}
+	if e.n > 0 && e.u > e.n {
+		e.l = bits.Len64(uint64(e.u/e.n)) - 1
+	}
+	if e.l > 0 {
+		e.lows = NewPacked(e.l, e.n)
+	}
+	e.highs = make([]bitInt, (e.n+e.u>>uint(e.l)+1+bitSize-1)/bitSize)
+	for i, x := range xs {
+		if e.lows != nil {
+			e.lows.Set(i, uint64(x))
+		}
+		pos := x>>uint(e.l) + i
+		e.highs[pos/bitSize] |= 1 << bitInt(pos%bitSize)
+	}
+	e.buildSamples()
+	return &e
+}
+
+// Len return numbers of elements
+func (e *EliasFano) Len() int {
+	return e.n
+}
+
+// Access return the i-th element, 0 if i is out of range
+func (e *EliasFano) Access(i int) int {
+	if i < 0 || i >= e.n {
+		return 0
+	}
+	return (e.select1(i)-i)<<uint(e.l) | e.low(i)
+}
+
+// NextGEQ return the smallest element not less than x
+// false is returned if there is no such element
+func (e *EliasFano) NextGEQ(x int) (int, bool) {
+	if x >= e.u {
+		return 0, false
+	}
+	if x < 0 {
+		x = 0
+	}
+	h := x >> uint(e.l)
+	pos := 0
+	if h > 0 {
+		pos = e.select0(h-1) + 1
+	}
+	// every zero before pos ends a high part, the rest are ones
+	i := pos - h
+	for ; i < e.n; pos++ {
+		if e.highs[pos/bitSize]&(1<<bitInt(pos%bitSize)) == 0 {
+			continue
+		}
+		if v := (pos-i)<<uint(e.l) | e.low(i); v >= x {
+			return v, true
+		}
+		i++
+	}
+	return 0, false
+}
+
+// Has return true if x is in the sequence
+func (e *EliasFano) Has(x int) bool {
+	v, ok := e.NextGEQ(x)
+	return ok && v == x
+}
+
+// Range call f with every element in order
+// stop if f return false
+func (e *EliasFano) Range(f func(x int) bool) {
+	i := 0
+	for w, word := range e.highs {
+		for word != 0 {
+			pos := w*bitSize + bits.TrailingZeros64(uint64(word))
+			if !f((pos-i)<<uint(e.l) | e.low(i)) {
+				return
+			}
+			i++
+			word &= word - 1
+		}
+	}
+}
+
+// Bitmap return a NBitmap of elements
+func (e *EliasFano) Bitmap() *NBitmap {
+	n := New()
+	e.Range(func(x int) bool {
+		n.Add(x)
+		return true
+	})
+	return n
+}
+
+// IntersectBitmap return a NBitmap of elements both in e and n
+func (e *EliasFano) IntersectBitmap(n *NBitmap) *NBitmap {
+	r := New()
+	e.Range(func(x int) bool {
+		if n.Has(x) {
+			r.Add(x)
+		}
+		return true
+	})
+	return r
+}
+
+// MarshalBinary encode the sequence as number of elements, universe and bits of low parts
+// as little endian uint64, then the low parts and the high parts as little endian uint64s
+func (e *EliasFano) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 24)
+	binary.LittleEndian.PutUint64(b, uint64(e.n))
+	binary.LittleEndian.PutUint64(b[8:], uint64(e.u))
+	binary.LittleEndian.PutUint64(b[16:], uint64(e.l))
+	if e.lows != nil {
+		b = appendWords(b, e.lows.words)
+	}
+	return appendWords(b, e.highs), nil
+}
+
+// UnmarshalBinary decode data encoded by MarshalBinary into the sequence
+func (e *EliasFano) UnmarshalBinary(data []byte) error {
+	if len(data) < 24 {
+		return errShortBuffer
+	}
+	n := binary.LittleEndian.Uint64(data)
+	u := binary.LittleEndian.Uint64(data[8:])
+	l := binary.LittleEndian.Uint64(data[16:])
+	if l >= bitSize || u > math.MaxInt || n > uint64(len(data))*8 || u>>l > uint64(len(data))*8 || (n == 0) != (u == 0) {
+		return errors.New("bitmap: invalid elias fano header")
+	}
+	new := EliasFano{n: int(n), u: int(u), l: int(l)}
+	data = data[24:]
+	if new.l > 0 {
+		new.lows = NewPacked(new.l, new.n)
+		size := (len(new.lows.words)*bitSize + 63) / 64
+		words, err := readWords(data, size)
+		if err != nil {
+			return err
+		}
+		copy(new.lows.words, words)
+		data = data[size*8:]
+	}
+	size := (new.n + new.u>>uint(new.l) + 1 + 63) / 64
+	words, err := readWords(data, size)
+	if err != nil {
+		return err
+	}
+	new.highs = words[:(new.n+new.u>>uint(new.l)+1+bitSize-1)/bitSize]
+	if popcount(new.highs) != new.n {
+		return errors.New("bitmap: invalid elias fano data")
+	}
+	new.buildSamples()
+	*e = new
+	return nil
+}
+
+// low return the low part of the i-th element
+func (e *EliasFano) low(i int) int {
+	if e.lows == nil {
+		return 0
+	}
+	return int(e.lows.Get(i))
+}
+
+// buildSamples record the position of every efSample-th one and zero in highs
+func (e *EliasFano) buildSamples() {
+	e.ones, e.zeros = nil, nil
+	ones, zeros := 0, 0
+	for pos := 0; pos < len(e.highs)*bitSize; pos++ {
+		if e.highs[pos/bitSize]&(1<<bitInt(pos%bitSize)) != 0 {
+			if ones%efSample == 0 {
+				e.ones = append(e.ones, pos)
+			}
+			ones++
+		} else {
+			if zeros%efSample == 0 {
+				e.zeros = append(e.zeros, pos)
+			}
+			zeros++
+		}
+	}
+}
+
+// select1 return the position of the k-th one in highs
+func (e *EliasFano) select1(k int) int {
+	return selectBit(e.highs, e.ones[k/efSample], k%efSample, false)
+}
+
+// select0 return the position of the k-th zero in highs
+func (e *EliasFano) select0(k int) int {
+	return selectBit(e.highs, e.zeros[k/efSample], k%efSample, true)
+}
+
+// selectBit return the position of the k-th one after pos, pos included
+// zeros are selected instead of ones if invert is true
+func selectBit(words []bitInt, pos int, k int, invert bool) int {
+	for i := pos / bitSize; i < len(words); i++ {
+		word := words[i]
+		if invert {
+			word = ^word
+		}
+		if i == pos/bitSize {
+			word &^= 1<<bitInt(pos%bitSize) - 1
+		}
+		count := bits.OnesCount64(uint64(word))
+		if k < count {
+			for ; k > 0; k-- {
+				word &= word - 1
+			}
+			return i*bitSize + bits.TrailingZeros64(uint64(word))
+		}
+		k -= count
+	}
+	return len(words) * bitSize
+}
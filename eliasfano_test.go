@@ -0,0 +1,124 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEliasFanoAccess(t *testing.T) {
+	var xs []int
+	for i := 0; i < 3000; i++ {
+		xs = append(xs, i*i/7+i/3)
+	}
+	e := bitmap.NewEliasFanoInts(xs)
+	if e.Len() != len(xs) {
+		t.Fatalf("TestEliasFanoAccess failed. Expected %d, Got %d", len(xs), e.Len())
+	}
+	for i, x := range xs {
+		if e.Access(i) != x {
+			t.Fatalf("TestEliasFanoAccess failed at %d. Expected %d, Got %d", i, x, e.Access(i))
+		}
+	}
+	if bitmap.NewEliasFanoInts([]int{3, 2}) != nil || bitmap.NewEliasFanoInts([]int{-1}) != nil ||
+		bitmap.NewEliasFanoInts([]int{1, math.MaxInt}) != nil {
+		t.Errorf("TestEliasFanoAccess failed. Expected nil for invalid input")
+	}
+	if e := bitmap.NewEliasFanoInts([]int{1, math.MaxInt - 1}); e == nil || e.Access(1) != math.MaxInt-1 {
+		t.Errorf("TestEliasFanoAccess failed. Expected %d, Got %v", math.MaxInt-1, e)
+	}
+}
+
+func TestEliasFanoNextGEQ(t *testing.T) {
+	e := bitmap.NewEliasFanoInts([]int{3, 3, 10, 1000, 1001, 70000})
+	cases := [][2]int{{-5, 3}, {0, 3}, {3, 3}, {4, 10}, {11, 1000}, {1001, 1001}, {1002, 70000}, {70000, 70000}}
+	for _, c := range cases {
+		if v, ok := e.NextGEQ(c[0]); !ok || v != c[1] {
+			t.Errorf("TestEliasFanoNextGEQ failed for %d. Expected %d, Got %d", c[0], c[1], v)
+		}
+	}
+	if _, ok := e.NextGEQ(70001); ok {
+		t.Errorf("TestEliasFanoNextGEQ failed. Expected none")
+	}
+	if !e.Has(1000) || e.Has(999) || e.Has(0) {
+		t.Errorf("TestEliasFanoNextGEQ Has failed.")
+	}
+	empty := bitmap.NewEliasFanoInts(nil)
+	if _, ok := empty.NextGEQ(0); ok || empty.Has(0) {
+		t.Errorf("TestEliasFanoNextGEQ failed. Expected empty")
+	}
+}
+
+func TestEliasFanoBitmap(t *testing.T) {
+	n := bitmap.New()
+	for i := 0; i < 100000; i += 7 {
+		n.Add(i)
+	}
+	e := bitmap.NewEliasFano(n)
+	if b := e.Bitmap(); b.String() != n.String() {
+		t.Errorf("TestEliasFanoBitmap failed.")
+	}
+	m := bitmap.New()
+	for i := 0; i < 100000; i += 5 {
+		m.Add(i)
+	}
+	expected := n.Copy()
+	expected.Intersect(m)
+	if r := e.IntersectBitmap(m); r.String() != expected.String() || r.Len() != expected.Len() {
+		t.Errorf("TestEliasFanoBitmap Intersect failed.")
+	}
+	count := 0
+	e.Range(func(x int) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Errorf("TestEliasFanoBitmap Range failed. Expected 10, Got %d", count)
+	}
+}
+
+func TestEliasFanoBinary(t *testing.T) {
+	for _, xs := range [][]int{nil, {0}, {5, 5, 5}, {1, 100, 10000, 1000000}} {
+		e := bitmap.NewEliasFanoInts(xs)
+		data, err := e.MarshalBinary()
+		if err != nil {
+			t.Fatalf("TestEliasFanoBinary failed. %v", err)
+		}
+		f := &bitmap.EliasFano{}
+		if err := f.UnmarshalBinary(data); err != nil {
+			t.Fatalf("TestEliasFanoBinary failed for %v. %v", xs, err)
+		}
+		if f.Len() != len(xs) {
+			t.Fatalf("TestEliasFanoBinary failed. Expected %d, Got %d", len(xs), f.Len())
+		}
+		for i, x := range xs {
+			if f.Access(i) != x {
+				t.Errorf("TestEliasFanoBinary failed. Expected %d, Got %d", x, f.Access(i))
+			}
+		}
+		if err := f.UnmarshalBinary(data[:len(data)-8]); err == nil {
+			t.Errorf("TestEliasFanoBinary failed. Expected error for short data")
+		}
+	}
+	// universe 1<<63 with 63 bits low parts
+	data := make([]byte, 24+16)
+	binary.LittleEndian.PutUint64(data, 1)
+	binary.LittleEndian.PutUint64(data[8:], 1<<63)
+	binary.LittleEndian.PutUint64(data[16:], 63)
+	data[32] = 1 // the only high part
+	if err := (&bitmap.EliasFano{}).UnmarshalBinary(data); err == nil {
+		t.Errorf("TestEliasFanoBinary failed. Expected error for too large universe")
+	}
+}
+
+func TestEliasFanoPortable(t *testing.T) {
+	// encoded bytes are the same on 32 and 64 bits platforms
+	e := bitmap.NewEliasFanoInts([]int{1, 100, 10000, 1 << 20})
+	data, _ := e.MarshalBinary()
+	expected := "040000000000000001001000000000001200000000000000010090010071020000000000000000008700000000000000"
+	if s := fmt.Sprintf("%x", data); s != expected {
+		t.Errorf("TestEliasFanoPortable failed. Expected %s, Got %s", expected, s)
+	}
+}
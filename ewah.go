@@ -0,0 +1,381 @@
+package bitmap
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// marker word layout of EWAH: the lowest bit is the bit of clean words,
+// then ewahRunBits bits for the number of clean words,
+// then the rest bits for the number of literal words following the marker
+const (
+	ewahRunBits = bitSize / 2
+	ewahLitBits = bitSize - 1 - ewahRunBits
+	ewahMaxRun  = 1<<ewahRunBits - 1
+	ewahMaxLit  = 1<<ewahLitBits - 1
+)
+
+// EWAH is a word-aligned hybrid compressed bitmap
+// runs of all zero or all one words are stored as a count in a marker word,
+// other words are stored as they are after the marker
+type EWAH struct {
+	len    int
+	words  int // number of uncompressed words
+	marker int // index of the last marker in buffer
+	buffer []bitInt
+}
+
+// NewEWAH return a new empty EWAH
+func NewEWAH() *EWAH {
+	return &EWAH{
+		buffer: make([]bitInt, 1, bitmapSize),
+	}
+}
+
+// NewEWAHFromBitmap return a new EWAH of elements in n
+func NewEWAHFromBitmap(n *NBitmap) *EWAH {
+	e := NewEWAH()
+	next := 0 // the next page to add
+	n.rangePages(func(p int, pg *page) bool {
+		if p > next {
+			e.addClean(false, (p-next)*pageWords)
+		}
+		for _, word := range pg.words {
+			e.addLiteral(word)
+		}
+		next = p + 1
+		return true
+	})
+	return e
+}
+
+// String return formated string of bitmap
+func (e *EWAH) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	e.Range(func(x int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers in bitmap
+func (e *EWAH) Len() int {
+	return e.len
+}
+
+// SizeInBytes return bytes used by compressed words
+func (e *EWAH) SizeInBytes() int {
+	return len(e.buffer) * bitSize / 8
+}
+
+// Has return true if x is in the bitmap
+func (e *EWAH) Has(x int) bool {
+	if x < 0 || x/bitSize >= e.words {
+		return false
+	}
+	word, bit := x/bitSize, bitInt(x%bitSize)
+	r := newEWAHReader(e)
+	for pos := 0; ; {
+		if r.run > 0 {
+			if word < pos+r.run {
+				return r.bit
+			}
+			pos += r.run
+			r.skip(r.run)
+			continue
+		}
+		if pos == word {
+			return r.next()&(1<<bit) != 0
+		}
+		r.next()
+		pos++
+	}
+}
+
+// Add add x to the bitmap
+// elements must be added in increasing order, x is ignored if not greater than
+// the largest element in the bitmap
+func (e *EWAH) Add(x int) {
+	if x < 0 {
+		return
+	}
+	word, bit := x/bitSize, bitInt(x%bitSize)
+	marker := e.buffer[e.marker]
+	lits := int(marker >> (1 + ewahRunBits))
+	run := int(marker >> 1 & ewahMaxRun)
+	if word < e.words && lits == 0 && marker&1 == 0 && e.words-word <= run {
+		// x is in the trailing run of zero words, cut the run before it
+		e.buffer[e.marker] -= bitInt(e.words-word) << 1
+		e.words = word
+	}
+	if word < e.words-1 || (word == e.words-1 && lits == 0) {
+		return
+	}
+	if word == e.words-1 {
+		last := e.buffer[len(e.buffer)-1]
+		if last>>bit != 0 {
+			return
+		}
+		e.buffer = e.buffer[:len(e.buffer)-1]
+		e.buffer[e.marker] -= 1 << (1 + ewahRunBits)
+		e.words--
+		e.len -= bits.OnesCount64(uint64(last))
+		e.addLiteral(last | 1<<bit)
+		return
+	}
+	e.addClean(false, word-e.words)
+	e.addLiteral(1 << bit)
+}
+
+// Range call f with every element in increasing order
+// stop if f return false
+func (e *EWAH) Range(f func(x int) bool) {
+	r := newEWAHReader(e)
+	for pos := 0; !r.done(); {
+		if r.run > 0 {
+			if r.bit {
+				for x := pos * bitSize; x < (pos+r.run)*bitSize; x++ {
+					if !f(x) {
+						return
+					}
+				}
+			}
+			pos += r.run
+			r.skip(r.run)
+			continue
+		}
+		word := r.next()
+		for word != 0 {
+			if !f(pos*bitSize + bits.TrailingZeros64(uint64(word))) {
+				return
+			}
+			word &= word - 1
+		}
+		pos++
+	}
+}
+
+// Bitmap return a NBitmap of elements
+func (e *EWAH) Bitmap() *NBitmap {
+	n := New()
+	r := newEWAHReader(e)
+	for pos := 0; !r.done(); {
+		if r.run > 0 {
+			if r.bit {
+				for i := pos; i < pos+r.run; i++ {
+					n.setWord(i, ^bitInt(0))
+				}
+			}
+			pos += r.run
+			r.skip(r.run)
+			continue
+		}
+		n.setWord(pos, r.next())
+		pos++
+	}
+	return n
+}
+
+// And return e & c
+// elements both in e and c
+func (e *EWAH) And(c *EWAH) *EWAH {
+	return ewahMerge(e, c, func(a, b bitInt) bitInt { return a & b })
+}
+
+// Or return e | c
+// elements in e or c
+func (e *EWAH) Or(c *EWAH) *EWAH {
+	return ewahMerge(e, c, func(a, b bitInt) bitInt { return a | b })
+}
+
+// Xor return (e - c) | (c - e)
+// elements only in e or only in c
+func (e *EWAH) Xor(c *EWAH) *EWAH {
+	return ewahMerge(e, c, func(a, b bitInt) bitInt { return a ^ b })
+}
+
+// AndNot return e - c
+// elements only in e
+func (e *EWAH) AndNot(c *EWAH) *EWAH {
+	return ewahMerge(e, c, func(a, b bitInt) bitInt { return a &^ b })
+}
+
+// Clear make the bitmap empty
+func (e *EWAH) Clear() {
+	*e = *NewEWAH()
+}
+
+// Copy return a copy bitmap
+func (e *EWAH) Copy() *EWAH {
+	new := EWAH{}
+	new.len = e.len
+	new.words = e.words
+	new.marker = e.marker
+	new.buffer = make([]bitInt, len(e.buffer))
+	copy(new.buffer, e.buffer)
+	return &new
+}
+
+// addClean append n clean words of bit
+func (e *EWAH) addClean(bit bool, n int) {
+	if n <= 0 {
+		return
+	}
+	e.words += n
+	if bit {
+		e.len += n * bitSize
+	}
+	for n > 0 {
+		marker := e.buffer[e.marker]
+		run := marker >> 1 & ewahMaxRun
+		if marker>>(1+ewahRunBits) != 0 || (run > 0 && (marker&1 == 1) != bit) || run == ewahMaxRun {
+			e.buffer = append(e.buffer, 0)
+			e.marker = len(e.buffer) - 1
+			marker, run = 0, 0
+		}
+		add := ewahMaxRun - run
+		if add > bitInt(n) {
+			add = bitInt(n)
+		}
+		marker = marker&^(ewahMaxRun<<1) | (run+add)<<1
+		if bit {
+			marker |= 1
+		}
+		e.buffer[e.marker] = marker
+		n -= int(add)
+	}
+}
+
+// addLiteral append word, clean words are counted in the marker instead
+func (e *EWAH) addLiteral(word bitInt) {
+	if word == 0 {
+		e.addClean(false, 1)
+		return
+	}
+	if word == ^bitInt(0) {
+		e.addClean(true, 1)
+		return
+	}
+	if int(e.buffer[e.marker]>>(1+ewahRunBits)) == ewahMaxLit {
+		e.buffer = append(e.buffer, 0)
+		e.marker = len(e.buffer) - 1
+	}
+	e.buffer[e.marker] += 1 << (1 + ewahRunBits)
+	e.buffer = append(e.buffer, word)
+	e.words++
+	e.len += bits.OnesCount64(uint64(word))
+}
+
+// ewahMerge return the bitmap of op applied to every word of a and b
+// clean runs are merged without decompression, the shorter bitmap is padded with zeros
+// zero words are only added before a nonzero word, so the result ends at its
+// largest element and Add can append to it
+func ewahMerge(a *EWAH, b *EWAH, op func(a, b bitInt) bitInt) *EWAH {
+	e := NewEWAH()
+	zeros := 0 // zero words not added yet
+	ra, rb := newEWAHReader(a), newEWAHReader(b)
+	for !ra.done() || !rb.done() {
+		if ra.run > 0 && rb.run > 0 {
+			n := ra.run
+			if rb.run < n {
+				n = rb.run
+			}
+			word := op(ewahClean(ra.bit), ewahClean(rb.bit))
+			ra.skip(n)
+			rb.skip(n)
+			if word == 0 {
+				zeros += n
+				continue
+			}
+			e.addClean(false, zeros)
+			e.addClean(true, n)
+			zeros = 0
+			continue
+		}
+		word := op(ra.next(), rb.next())
+		if word == 0 {
+			zeros++
+			continue
+		}
+		e.addClean(false, zeros)
+		e.addLiteral(word)
+		zeros = 0
+	}
+	return e
+}
+
+// ewahClean return a clean word of bit
+func ewahClean(bit bool) bitInt {
+	if bit {
+		return ^bitInt(0)
+	}
+	return 0
+}
+
+// ewahReader read words of EWAH in order
+// after the last word it reads an endless run of zero words
+type ewahReader struct {
+	buffer []bitInt
+	i      int  // index of the next word in buffer
+	run    int  // clean words left in current run
+	bit    bool // bit of current run
+	lits   int  // literal words left after current run
+	end    bool // all words are read
+}
+
+func newEWAHReader(e *EWAH) *ewahReader {
+	r := &ewahReader{buffer: e.buffer}
+	r.load()
+	return r
+}
+
+// load read markers until there are words left or the buffer ends
+func (r *ewahReader) load() {
+	for r.run == 0 && r.lits == 0 {
+		if r.i >= len(r.buffer) {
+			r.run, r.bit, r.end = math.MaxInt32, false, true
+			return
+		}
+		marker := r.buffer[r.i]
+		r.run = int(marker >> 1 & ewahMaxRun)
+		r.bit = marker&1 == 1
+		r.lits = int(marker >> (1 + ewahRunBits))
+		r.i++
+	}
+}
+
+// done return true if all words are read
+func (r *ewahReader) done() bool {
+	return r.end
+}
+
+// skip skip n clean words, n must not exceed run
+func (r *ewahReader) skip(n int) {
+	if r.done() {
+		return
+	}
+	r.run -= n
+	r.load()
+}
+
+// next return the next word
+func (r *ewahReader) next() bitInt {
+	if r.run > 0 {
+		word := ewahClean(r.bit)
+		r.skip(1)
+		return word
+	}
+	word := r.buffer[r.i]
+	r.i++
+	r.lits--
+	r.load()
+	return word
+}
@@ -0,0 +1,116 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func ewahSample(step int, runStart int, runEnd int) *bitmap.NBitmap {
+	n := bitmap.New()
+	for i := 0; i < runStart; i += step {
+		n.Add(i)
+	}
+	for i := runStart; i < runEnd; i++ {
+		n.Add(i)
+	}
+	for i := runEnd + 1000; i < runEnd+3000; i += step {
+		n.Add(i)
+	}
+	return n
+}
+
+func TestEWAHAdd(t *testing.T) {
+	e := bitmap.NewEWAH()
+	e.Add(-1)
+	e.Add(0)
+	e.Add(1)
+	e.Add(1)
+	e.Add(0)
+	e.Add(10000)
+	e.Add(500)
+	for i := 20000; i < 30000; i++ {
+		e.Add(i)
+	}
+	if e.Len() != 10003 || !e.Has(0) || !e.Has(1) || e.Has(2) || !e.Has(10000) || e.Has(500) ||
+		!e.Has(25000) || e.Has(30000) || e.Has(-1) {
+		t.Errorf("TestEWAHAdd failed. Got %d", e.Len())
+	}
+	c := bitmap.NewEWAH()
+	c.Add(3)
+	c.Add(70)
+	if c.String() != "{3 70}" {
+		t.Errorf("TestEWAHAdd failed. Expected {3 70}, Got %s", c.String())
+	}
+}
+
+func TestEWAHBitmap(t *testing.T) {
+	n := ewahSample(7, 5000, 100000)
+	e := bitmap.NewEWAHFromBitmap(n)
+	if e.Len() != n.Len() || e.String() != n.String() {
+		t.Errorf("TestEWAHBitmap failed. Expected %d, Got %d", n.Len(), e.Len())
+	}
+	if e.SizeInBytes() > 3000 {
+		t.Errorf("TestEWAHBitmap failed. Expected runs compressed, Got %d bytes", e.SizeInBytes())
+	}
+	if b := e.Bitmap(); b.String() != n.String() || b.Len() != n.Len() {
+		t.Errorf("TestEWAHBitmap failed.")
+	}
+	for i := 0; i < 110000; i += 13 {
+		if e.Has(i) != n.Has(i) {
+			t.Fatalf("TestEWAHBitmap Has failed for %d.", i)
+		}
+	}
+	e.Add(200000)
+	if !e.Has(200000) || e.Len() != n.Len()+1 {
+		t.Errorf("TestEWAHBitmap Add failed.")
+	}
+}
+
+func TestEWAHSets(t *testing.T) {
+	a := ewahSample(3, 1000, 50000)
+	b := ewahSample(5, 30000, 80000)
+	ea, eb := bitmap.NewEWAHFromBitmap(a), bitmap.NewEWAHFromBitmap(b)
+	check := func(name string, got *bitmap.EWAH, op func(n *bitmap.NBitmap, c *bitmap.NBitmap)) {
+		expected := a.Copy()
+		op(expected, b)
+		if got.String() != expected.String() || got.Len() != expected.Len() {
+			t.Errorf("TestEWAHSets %s failed. Expected %d elements, Got %d", name, expected.Len(), got.Len())
+		}
+	}
+	check("And", ea.And(eb), (*bitmap.NBitmap).Intersect)
+	check("Or", ea.Or(eb), func(n *bitmap.NBitmap, c *bitmap.NBitmap) { n.Union(c) })
+	check("Xor", ea.Xor(eb), func(n *bitmap.NBitmap, c *bitmap.NBitmap) { n.SymExcept(c) })
+	check("AndNot", ea.AndNot(eb), (*bitmap.NBitmap).Except)
+	check("Or empty", ea.Or(bitmap.NewEWAH()), func(n *bitmap.NBitmap, c *bitmap.NBitmap) {})
+}
+
+func TestEWAHSetsAdd(t *testing.T) {
+	// results end at their largest element, so Add can append after it
+	a, b := bitmap.NewEWAH(), bitmap.NewEWAH()
+	a.Add(1)
+	a.Add(200)
+	b.Add(1)
+	b.Add(300)
+	check := func(name string, got *bitmap.EWAH, x int, expected string) {
+		got.Add(x)
+		if got.String() != expected {
+			t.Errorf("TestEWAHSetsAdd %s failed. Expected %s, Got %s", name, expected, got.String())
+		}
+	}
+	check("And", a.And(b), 6, "{1 6}")
+	check("Or", a.Or(b), 301, "{1 200 300 301}")
+	check("Xor", a.Xor(b), 400, "{200 300 400}")
+	check("AndNot", a.AndNot(b), 250, "{200 250}")
+	check("AndNot empty", a.AndNot(a), 0, "{0}")
+}
+
+func TestEWAHCopy(t *testing.T) {
+	e := bitmap.NewEWAH()
+	e.Add(1)
+	c := e.Copy()
+	e.Add(2)
+	e.Clear()
+	if c.String() != "{1}" || e.Len() != 0 {
+		t.Errorf("TestEWAHCopy failed. Expected {1}, Got %s", c.String())
+	}
+}
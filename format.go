@@ -0,0 +1,129 @@
+package bitmap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// formatCount print str for %v, %d and %s, countStr for %+v and %+d
+func formatCount(f fmt.State, verb rune, str func() string, countStr func() string) {
+	switch verb {
+	case 'v', 'd':
+		if f.Flag('+') {
+			fmt.Fprint(f, countStr())
+			return
+		}
+		fmt.Fprint(f, str())
+	case 's':
+		fmt.Fprint(f, str())
+	default:
+		fmt.Fprintf(f, "%%!%c(%s)", verb, str())
+	}
+}
+
+// ParseC parse string formated by CountString or String into a CBitmap
+// the bitmap can count to n, if n <= 0 the largest count in s is used
+func ParseC(s string, n int) (*CBitmap, error) {
+	xs, counts, err := parseCounts(s)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		n = maxCount(counts)
+	}
+	c := NewC(n)
+	if c == nil {
+		return nil, fmt.Errorf("bitmap: invalid count limit %d", n)
+	}
+	for i, x := range xs {
+		if x < 0 {
+			return nil, fmt.Errorf("bitmap: element %d out of range", x)
+		}
+		if counts[i] > n {
+			return nil, fmt.Errorf("bitmap: count %d of %d exceeds %d", counts[i], x, n)
+		}
+		word, bit := x/c.bitSize, bitInt(x%c.bitSize*c.numSize)
+		if word >= len(c.words) {
+			c.words = append(c.words, make([]bitInt, word+1-len(c.words))...)
+		}
+		c.words[word] |= bitInt(counts[i]) << bit
+		c.len++
+	}
+	return c, nil
+}
+
+// ParseRC parse string formated by CountString or String into a RCBitmap
+// the bitmap count [start, end) and can count to n, if n <= 0 the largest count in s is used
+func ParseRC(s string, start int, end int, n int) (*RCBitmap, error) {
+	xs, counts, err := parseCounts(s)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		n = maxCount(counts)
+	}
+	rc := NewRC(start, end, n)
+	if rc == nil {
+		return nil, fmt.Errorf("bitmap: invalid range [%d, %d) or count limit %d", start, end, n)
+	}
+	for i, x := range xs {
+		if x < start || x >= end {
+			return nil, fmt.Errorf("bitmap: element %d out of range [%d, %d)", x, start, end)
+		}
+		if counts[i] > n {
+			return nil, fmt.Errorf("bitmap: count %d of %d exceeds %d", counts[i], x, n)
+		}
+		x -= start
+		word, bit := x/rc.bitSize, bitInt(x%rc.bitSize*rc.numSize)
+		if word >= len(rc.words) {
+			rc.words = append(rc.words, make([]bitInt, word+1-len(rc.words))...)
+		}
+		rc.words[word] |= bitInt(counts[i]) << bit
+		rc.len++
+	}
+	return rc, nil
+}
+
+// parseCounts parse {x:count x ...} into elements and counts
+// an element without count is counted once
+func parseCounts(s string) ([]int, []int, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, nil, fmt.Errorf("bitmap: invalid format %q", s)
+	}
+	fields := strings.Fields(s[1 : len(s)-1])
+	xs := make([]int, 0, len(fields))
+	counts := make([]int, 0, len(fields))
+	for _, field := range fields {
+		elem, num, found := strings.Cut(field, ":")
+		x, err := strconv.Atoi(elem)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bitmap: invalid element %q", field)
+		}
+		count := 1
+		if found {
+			count, err = strconv.Atoi(num)
+			if err != nil || count <= 0 {
+				return nil, nil, fmt.Errorf("bitmap: invalid count %q", field)
+			}
+		}
+		if len(xs) > 0 && x <= xs[len(xs)-1] {
+			return nil, nil, fmt.Errorf("bitmap: element %d is not in increasing order", x)
+		}
+		xs = append(xs, x)
+		counts = append(counts, count)
+	}
+	return xs, counts, nil
+}
+
+// maxCount return the largest count, at least 1
+func maxCount(counts []int) int {
+	n := 1
+	for _, count := range counts {
+		if count > n {
+			n = count
+		}
+	}
+	return n
+}
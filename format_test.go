@@ -0,0 +1,86 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"fmt"
+	"testing"
+)
+
+func TestCRange(t *testing.T) {
+	b := bitmap.NewC(3)
+	b.Add(10)
+	b.Add(10)
+	b.Add(100)
+	b.Add(63)
+	var s string
+	b.Range(func(x int, count int) bool {
+		s += fmt.Sprintf("%d:%d ", x, count)
+		return true
+	})
+	if s != "10:2 63:1 100:1 " {
+		t.Errorf("TestCRange failed. Expected 10:2 63:1 100:1, Got %s", s)
+	}
+}
+
+func TestCountString(t *testing.T) {
+	b := bitmap.NewC(3)
+	b.Add(10)
+	b.Add(10)
+	b.Add(10)
+	b.Add(100)
+	if b.CountString() != "{10:3 100:1}" {
+		t.Errorf("TestCountString failed. Expected {10:3 100:1}, Got %s", b.CountString())
+	}
+	if s := fmt.Sprintf("%v %+v %d", b, b, b); s != "{10 100} {10:3 100:1} {10 100}" {
+		t.Errorf("TestCountString Format failed. Expected {10 100} {10:3 100:1} {10 100}, Got %s", s)
+	}
+	rc := bitmap.NewRC(-5, 5, 3)
+	rc.Add(-5)
+	rc.Add(4)
+	rc.Add(4)
+	if s := fmt.Sprintf("%s %+v", rc, rc); s != "{-5 4} {-5:1 4:2}" {
+		t.Errorf("TestCountString RC failed. Expected {-5 4} {-5:1 4:2}, Got %s", s)
+	}
+}
+
+func TestParseC(t *testing.T) {
+	b := bitmap.NewC(5)
+	for i := 0; i < 5; i++ {
+		b.Add(10)
+	}
+	b.Add(100)
+	b.Add(10000)
+	b.Add(10000)
+	c, err := bitmap.ParseC(b.CountString(), 5)
+	if err != nil || c.CountString() != b.CountString() || c.Len() != 3 {
+		t.Errorf("TestParseC failed. Expected %s, Got %v %v", b.CountString(), c, err)
+	}
+	c, err = bitmap.ParseC("{1 2:7}", 0)
+	if err != nil || c.Count(1) != 1 || c.Count(2) != 7 {
+		t.Errorf("TestParseC infer failed. Got %v", err)
+	}
+	c.Add(2)
+	if c.Count(2) != 7 {
+		t.Errorf("TestParseC infer failed. Expected 7, Got %d", c.Count(2))
+	}
+	for _, s := range []string{"", "{1 2", "{a}", "{1:0}", "{2 1}", "{-1}", "{1:9}"} {
+		if _, err := bitmap.ParseC(s, 5); err == nil {
+			t.Errorf("TestParseC failed. Expected error for %q", s)
+		}
+	}
+}
+
+func TestParseRC(t *testing.T) {
+	b := bitmap.NewRC(-10, 10, 3)
+	b.Add(-10)
+	b.Add(0)
+	b.Add(0)
+	b.Add(9)
+	c, err := bitmap.ParseRC(b.CountString(), -10, 10, 3)
+	if err != nil || c.CountString() != "{-10:1 0:2 9:1}" || c.Len() != 3 {
+		t.Errorf("TestParseRC failed. Expected {-10:1 0:2 9:1}, Got %v %v", c, err)
+	}
+	if _, err := bitmap.ParseRC("{10}", -10, 10, 3); err == nil {
+		t.Errorf("TestParseRC failed. Expected error for out of range")
+	}
+}
@@ -0,0 +1,300 @@
+package bitmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"unsafe"
+)
+
+// binary format of NBitmap, all little endian uint64s:
+// numbers in bitmap and number of pages,
+// then the page index and numbers before the page of every page,
+// then pageWords words of every page
+const (
+	frozenHeader = 16
+	frozenEntry  = 16
+	frozenPage   = pageWords * 8
+)
+
+// nativeLittle is true if words are little endian in memory,
+// so the bytes of the binary format can be used as words
+var nativeLittle = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// MarshalBinary encode the bitmap, only pages with elements are encoded
+func (n *NBitmap) MarshalBinary() ([]byte, error) {
+	pages := 0
+	n.rangePages(func(p int, pg *page) bool {
+		pages++
+		return true
+	})
+	b := make([]byte, frozenHeader+frozenEntry*pages, frozenHeader+(frozenEntry+frozenPage)*pages)
+	binary.LittleEndian.PutUint64(b, uint64(n.len))
+	binary.LittleEndian.PutUint64(b[8:], uint64(pages))
+	i, rank := 0, 0
+	n.rangePages(func(p int, pg *page) bool {
+		binary.LittleEndian.PutUint64(b[frozenHeader+frozenEntry*i:], uint64(p))
+		binary.LittleEndian.PutUint64(b[frozenHeader+frozenEntry*i+8:], uint64(rank))
+		rank += pg.count
+		i++
+		return true
+	})
+	n.rangePages(func(p int, pg *page) bool {
+		b = appendWords(b, pg.words[:])
+		return true
+	})
+	return b, nil
+}
+
+// UnmarshalBinary decode data encoded by MarshalBinary into the bitmap
+// words are checked by Frozen.Validate, the memory budget is kept but not checked
+func (n *NBitmap) UnmarshalBinary(data []byte) error {
+	f, err := NewFrozen(data)
+	if err != nil {
+		return err
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	new := f.Bitmap()
+	new.budget = n.budget
+	*n = *new
+	return nil
+}
+
+// Frozen is a read only NBitmap over bytes encoded by NBitmap.MarshalBinary
+// the bytes are used as words without copying if they are aligned,
+// so they must not be changed while the Frozen is used
+type Frozen struct {
+	len   int
+	index []uint64 // page index and numbers before the page of every page
+	words []bitInt // words of every page
+}
+
+// NewFrozen return a Frozen bitmap of data encoded by NBitmap.MarshalBinary
+// only the header and the index are checked, so words of a memory-mapped
+// file are not read, use Validate to check the words match the ranks
+func NewFrozen(data []byte) (*Frozen, error) {
+	if len(data) < frozenHeader {
+		return nil, errShortBuffer
+	}
+	length := binary.LittleEndian.Uint64(data)
+	pages := binary.LittleEndian.Uint64(data[8:])
+	if length > math.MaxInt || pages > uint64(len(data)-frozenHeader)/(frozenEntry+frozenPage) {
+		return nil, errors.New("bitmap: invalid bitmap header")
+	}
+	f := &Frozen{len: int(length)}
+	size := int(pages)
+	if size > 0 {
+		data = data[frozenHeader:]
+		if nativeLittle && uintptr(unsafe.Pointer(&data[0]))%8 == 0 {
+			f.index = unsafe.Slice((*uint64)(unsafe.Pointer(&data[0])), 2*size)
+			f.words = unsafe.Slice((*bitInt)(unsafe.Pointer(&data[frozenEntry*size])), pageWords*size)
+		} else {
+			f.index = make([]uint64, 2*size)
+			for i := range f.index {
+				f.index[i] = binary.LittleEndian.Uint64(data[8*i:])
+			}
+			f.words, _ = readWords(data[frozenEntry*size:], pageWords*size)
+		}
+	}
+	// pages are increasing, the first rank is 0, and every page has
+	// 1 to pageBits numbers by the ranks
+	for i := 0; i < size; i++ {
+		p, rank, next := f.index[2*i], f.index[2*i+1], length
+		if i+1 < size {
+			next = f.index[2*i+3]
+		}
+		if p > math.MaxInt/pageBits || (i > 0 && p <= f.index[2*i-2]) || (i == 0 && rank != 0) ||
+			next <= rank || next-rank > pageBits {
+			return nil, errors.New("bitmap: invalid bitmap index")
+		}
+	}
+	if size == 0 && length != 0 {
+		return nil, errors.New("bitmap: invalid bitmap index")
+	}
+	return f, nil
+}
+
+// Validate return an error if numbers in a page do not match the ranks in the index,
+// Rank and Len trust the index, it reads every word once
+func (f *Frozen) Validate() error {
+	size := len(f.index) / 2
+	for i := 0; i < size; i++ {
+		next := uint64(f.len)
+		if i+1 < size {
+			next = f.index[2*i+3]
+		}
+		if uint64(popcount(f.page(i))) != next-f.index[2*i+1] {
+			return errors.New("bitmap: invalid bitmap data")
+		}
+	}
+	return nil
+}
+
+// find return the index of the p-th page in pages, false if it's not found
+// the index is where the page should be if it's not found
+func (f *Frozen) find(p int) (int, bool) {
+	size := len(f.index) / 2
+	i := sort.Search(size, func(i int) bool {
+		return f.index[2*i] >= uint64(p)
+	})
+	return i, i < size && f.index[2*i] == uint64(p)
+}
+
+// page return words of the i-th page
+func (f *Frozen) page(i int) []bitInt {
+	return f.words[i*pageWords : (i+1)*pageWords]
+}
+
+// String return formated string of bitmap
+func (f *Frozen) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	f.Range(func(x int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers in bitmap
+func (f *Frozen) Len() int {
+	return f.len
+}
+
+// Has return true if x is in the bitmap
+func (f *Frozen) Has(x int) bool {
+	if x < 0 {
+		return false
+	}
+	i, ok := f.find(x / pageBits)
+	return ok && f.page(i)[x%pageBits/bitSize]&(1<<bitInt(x%bitSize)) != 0
+}
+
+// Range call f with every element in increasing order
+// stop if fn return false
+func (f *Frozen) Range(fn func(x int) bool) {
+	for i := 0; i < len(f.index)/2; i++ {
+		base := int(f.index[2*i]) * pageBits
+		for j, word := range f.page(i) {
+			for word != 0 {
+				if !fn(base + j*bitSize + bits.TrailingZeros64(uint64(word))) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// Rank return numbers in bitmap less than or equal to x
+func (f *Frozen) Rank(x int) int {
+	if x < 0 {
+		return 0
+	}
+	i, ok := f.find(x / pageBits)
+	if !ok {
+		if i == len(f.index)/2 {
+			return f.len
+		}
+		return int(f.index[2*i+1])
+	}
+	words := f.page(i)
+	word := x % pageBits / bitSize
+	return int(f.index[2*i+1]) + popcount(words[:word]) +
+		bits.OnesCount64(uint64(words[word]&(2<<bitInt(x%bitSize)-1)))
+}
+
+// Bitmap return a NBitmap copy of the bitmap
+func (f *Frozen) Bitmap() *NBitmap {
+	n := New()
+	for i := 0; i < len(f.index)/2; i++ {
+		n.setPage(int(f.index[2*i]), f.page(i), nil, func(x, y bitInt) bitInt { return x })
+	}
+	return n
+}
+
+// Union return f | c
+// elements in f or c
+func (f *Frozen) Union(c *Frozen) *NBitmap {
+	return f.merge(c, func(x, y bitInt) bitInt { return x | y }, true, true)
+}
+
+// Intersect return f & c
+// elements both in f and c
+func (f *Frozen) Intersect(c *Frozen) *NBitmap {
+	return f.merge(c, func(x, y bitInt) bitInt { return x & y }, false, false)
+}
+
+// Except return f - c
+// elements only in f
+func (f *Frozen) Except(c *Frozen) *NBitmap {
+	return f.merge(c, func(x, y bitInt) bitInt { return x &^ y }, true, false)
+}
+
+// SymExcept return (f - c) | (c - f)
+// elements only in f or only in c
+func (f *Frozen) SymExcept(c *Frozen) *NBitmap {
+	return f.merge(c, func(x, y bitInt) bitInt { return x ^ y }, true, true)
+}
+
+// merge return op of f and c page by page
+// pages only in f are kept if onlyF, pages only in c are kept if onlyC
+func (f *Frozen) merge(c *Frozen, op func(x, y bitInt) bitInt, onlyF bool, onlyC bool) *NBitmap {
+	n := New()
+	i, j := 0, 0
+	for i < len(f.index)/2 || j < len(c.index)/2 {
+		switch {
+		case j >= len(c.index)/2 || (i < len(f.index)/2 && f.index[2*i] < c.index[2*j]):
+			if onlyF {
+				n.setPage(int(f.index[2*i]), f.page(i), nil, op)
+			}
+			i++
+		case i >= len(f.index)/2 || c.index[2*j] < f.index[2*i]:
+			if onlyC {
+				n.setPage(int(c.index[2*j]), nil, c.page(j), op)
+			}
+			j++
+		default:
+			n.setPage(int(f.index[2*i]), f.page(i), c.page(j), op)
+			i++
+			j++
+		}
+	}
+	return n
+}
+
+// setPage set the p-th page of n to op of x and y word by word,
+// nil x or y is a page of zero words
+func (n *NBitmap) setPage(p int, x []bitInt, y []bitInt, op func(x, y bitInt) bitInt) {
+	var words [pageWords]bitInt
+	for i := range words {
+		var a, b bitInt
+		if x != nil {
+			a = x[i]
+		}
+		if y != nil {
+			b = y[i]
+		}
+		words[i] = op(a, b)
+	}
+	count := popcount(words[:])
+	if count == 0 {
+		return
+	}
+	pg := n.page(p)
+	pg.words, pg.count = words, count
+	n.len += count
+}
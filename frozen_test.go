@@ -0,0 +1,129 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestFrozen(t *testing.T) {
+	b := bitmap.New()
+	for _, x := range []int{1, 5, 64, 4095, 4096, 100000, 1 << 30} {
+		b.Add(x)
+	}
+	data, _ := b.MarshalBinary()
+	f, err := bitmap.NewFrozen(data)
+	if err != nil || f.String() != b.String() || f.Len() != 7 {
+		t.Fatalf("TestFrozen failed. Expected %s, Got %v %v", b.String(), f, err)
+	}
+	if !f.Has(4096) || f.Has(4097) || !f.Has(1<<30) || f.Has(-1) || f.Has(1<<30+1) {
+		t.Errorf("TestFrozen Has failed. Got %s", f.String())
+	}
+	for x, expected := range map[int]int{-1: 0, 0: 0, 1: 1, 63: 2, 64: 3, 5000: 5, 100000: 6, 1 << 29: 6, 1 << 30: 7, 1<<30 + 5000: 7} {
+		if rank := f.Rank(x); rank != expected {
+			t.Errorf("TestFrozen Rank failed for %d. Expected %d, Got %d", x, expected, rank)
+		}
+	}
+	if c := f.Bitmap(); c.String() != b.String() || c.Len() != b.Len() {
+		t.Errorf("TestFrozen Bitmap failed. Expected %s, Got %s", b.String(), c.String())
+	}
+	// the words are read from data
+	data[len(data)-1] = 0x80
+	if !f.Has(1<<30 + 4095) {
+		t.Errorf("TestFrozen failed. Expected words shared with data")
+	}
+	// the count of the last page does not match, only Validate read the words
+	if g, err := bitmap.NewFrozen(data); err != nil || g.Validate() == nil {
+		t.Errorf("TestFrozen failed. Expected Validate error for wrong count, Got %v", err)
+	}
+	var n bitmap.NBitmap
+	if err := n.UnmarshalBinary(data); err == nil {
+		t.Errorf("TestFrozen UnmarshalBinary failed. Expected error for wrong count")
+	}
+	data[len(data)-1] = 0
+	// unaligned data is copied
+	unaligned := make([]byte, len(data)+1)
+	copy(unaligned[1:], data)
+	if u, err := bitmap.NewFrozen(unaligned[1:]); err != nil || !u.Has(1<<30) || u.Rank(1<<30) != 7 {
+		t.Errorf("TestFrozen unaligned failed. Got %v", err)
+	}
+	if err := f.Validate(); err != nil {
+		t.Errorf("TestFrozen Validate failed. Got %v", err)
+	}
+	// the rank of the second page is the count of the first page
+	bad := append([]byte(nil), data...)
+	bad[16+16+8]--
+	if g, err := bitmap.NewFrozen(bad); err != nil || g.Validate() == nil {
+		t.Errorf("TestFrozen failed. Expected Validate error for wrong rank, Got %v", err)
+	}
+	// ranks must increase by 1 to 4096 numbers in the index
+	bad = append([]byte(nil), data...)
+	bad[16+16+8] = 0
+	if _, err := bitmap.NewFrozen(bad); err == nil {
+		t.Errorf("TestFrozen failed. Expected error for empty page in index")
+	}
+	bad = append([]byte(nil), data...)
+	bad[16+8] = 1
+	if _, err := bitmap.NewFrozen(bad); err == nil {
+		t.Errorf("TestFrozen failed. Expected error for first rank not 0")
+	}
+	for _, n := range []int{0, 15, 17, len(data) - 1} {
+		if _, err := bitmap.NewFrozen(data[:n]); err == nil {
+			t.Errorf("TestFrozen failed. Expected error for %d bytes", n)
+		}
+	}
+}
+
+func TestFrozenSets(t *testing.T) {
+	b := bitmap.New()
+	c := bitmap.New()
+	for i := 0; i < 20000; i += 3 {
+		b.Add(i)
+	}
+	for i := 0; i < 30000; i += 5 {
+		c.Add(i)
+	}
+	c.Add(1 << 30)
+	bd, _ := b.MarshalBinary()
+	cd, _ := c.MarshalBinary()
+	fb, _ := bitmap.NewFrozen(bd)
+	fc, _ := bitmap.NewFrozen(cd)
+	cases := []struct {
+		name   string
+		frozen func(f, c *bitmap.Frozen) *bitmap.NBitmap
+		op     func(b, c *bitmap.NBitmap)
+	}{
+		{"Union", (*bitmap.Frozen).Union, func(b, c *bitmap.NBitmap) { b.Union(c) }},
+		{"Intersect", (*bitmap.Frozen).Intersect, (*bitmap.NBitmap).Intersect},
+		{"Except", (*bitmap.Frozen).Except, (*bitmap.NBitmap).Except},
+		{"SymExcept", (*bitmap.Frozen).SymExcept, func(b, c *bitmap.NBitmap) { b.SymExcept(c) }},
+	}
+	for _, tc := range cases {
+		expected := b.Copy()
+		tc.op(expected, c)
+		got := tc.frozen(fb, fc)
+		if got.String() != expected.String() || got.Len() != expected.Len() {
+			t.Errorf("TestFrozenSets %s failed. Expected %d elements, Got %d", tc.name, expected.Len(), got.Len())
+		}
+	}
+	var n bitmap.NBitmap
+	n.SetBudget(1024)
+	if err := n.UnmarshalBinary(cd); err != nil || n.String() != c.String() {
+		t.Errorf("TestFrozenSets UnmarshalBinary failed. Got %v", err)
+	}
+	if err := n.TryAdd(1 << 29); err != bitmap.ErrBudget {
+		t.Errorf("TestFrozenSets UnmarshalBinary failed. Expected budget kept, Got %v", err)
+	}
+}
+
+func BenchmarkFrozen(b *testing.B) {
+	bm := bitmap.New()
+	for i := 0; i < 10000000; i += 7 {
+		bm.Add(i)
+	}
+	data, _ := bm.MarshalBinary()
+	f, _ := bitmap.NewFrozen(data)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Has(i % 10000000)
+	}
+}
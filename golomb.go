@@ -0,0 +1,219 @@
+package bitmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+// golombSample is the distance of sampled elements used by Has
+const golombSample = 64
+
+// GolombSet is a static probabilistic set of integers
+// elements are hashed into [0, n*2^p) and the sorted hashes are stored as
+// Golomb-Rice coded deltas, about p+2 bits per element with false positive rate 1/2^p
+type GolombSet struct {
+	n    int    // number of elements
+	p    int    // Rice parameter
+	data []byte // coded deltas, most significant bit first
+	// hash of every golombSample-th element and the bit offset after it,
+	// so Has only decode deltas from the nearest sample
+	values  []uint64
+	offsets []int
+}
+
+// NewGolomb return a new GolombSet of elements in n with false positive rate 1/2^p
+func NewGolomb(n *NBitmap, p int) *GolombSet {
+	xs := make([]int, 0, n.Len())
+	n.Range(func(x int) bool {
+		xs = append(xs, x)
+		return true
+	})
+	return NewGolombInts(xs, p)
+}
+
+// NewGolombInts return a new GolombSet of xs with false positive rate 1/2^p
+func NewGolombInts(xs []int, p int) *GolombSet {
+	if p <= 0 || p > 32 {
+		return nil
+	}
+	g := GolombSet{}
+	g.n = len(xs)
+	g.p = p
+	hashes := make([]uint64, len(xs))
+	for i, x := range xs {
+		hashes[i] = g.hash(x)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	w := bitWriter{}
+	last := uint64(0)
+	for _, h := range hashes {
+		delta := h - last
+		last = h
+		for q := delta >> uint(p); q > 0; q-- {
+			w.writeBit(1)
+		}
+		w.writeBit(0)
+		w.writeBits(delta, p)
+	}
+	g.data = w.data
+	g.buildSamples()
+	return &g
+}
+
+// Len return numbers of elements in set
+func (g *GolombSet) Len() int {
+	return g.n
+}
+
+// Has return true if x may be in the set
+// at most golombSample deltas are decoded
+func (g *GolombSet) Has(x int) bool {
+	if g.n == 0 {
+		return false
+	}
+	h := g.hash(x)
+	j := sort.Search(len(g.values), func(j int) bool {
+		return g.values[j] > h
+	}) - 1
+	if j < 0 {
+		return false
+	}
+	r := bitReader{data: g.data, n: g.offsets[j]}
+	value := g.values[j]
+	for i := j*golombSample + 1; i < g.n && i < (j+1)*golombSample && value < h; i++ {
+		value += r.readDelta(g.p)
+	}
+	return value == h
+}
+
+// HasMany return whether every element of xs may be in the set
+// the set is decoded only once, so it's faster than calling Has for many elements
+func (g *GolombSet) HasMany(xs []int) []bool {
+	found := make([]bool, len(xs))
+	if g.n == 0 || len(xs) == 0 {
+		return found
+	}
+	order := make([]int, len(xs))
+	hashes := make([]uint64, len(xs))
+	for i, x := range xs {
+		order[i] = i
+		hashes[i] = g.hash(x)
+	}
+	sort.Slice(order, func(i, j int) bool { return hashes[order[i]] < hashes[order[j]] })
+	r := bitReader{data: g.data}
+	value, decoded := uint64(0), 0
+	for _, i := range order {
+		for decoded < g.n && (decoded == 0 || value < hashes[i]) {
+			value += r.readDelta(g.p)
+			decoded++
+		}
+		found[i] = decoded > 0 && value == hashes[i]
+	}
+	return found
+}
+
+// MarshalBinary encode the set as number of elements and Rice parameter
+// as little endian uint64, then the coded deltas
+func (g *GolombSet) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 16, 16+len(g.data))
+	binary.LittleEndian.PutUint64(b, uint64(g.n))
+	binary.LittleEndian.PutUint64(b[8:], uint64(g.p))
+	return append(b, g.data...), nil
+}
+
+// UnmarshalBinary decode data encoded by MarshalBinary into the set
+func (g *GolombSet) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return errShortBuffer
+	}
+	n := binary.LittleEndian.Uint64(data)
+	p := binary.LittleEndian.Uint64(data[8:])
+	if p == 0 || p > 32 {
+		return errors.New("bitmap: invalid golomb set header")
+	}
+	// every element use at least p+1 bits
+	if n > uint64(len(data)-16)*8/(p+1) {
+		return errShortBuffer
+	}
+	g.n = int(n)
+	g.p = int(p)
+	g.data = append([]byte(nil), data[16:]...)
+	g.buildSamples()
+	return nil
+}
+
+// buildSamples record the hash of every golombSample-th element and the bit offset after it
+func (g *GolombSet) buildSamples() {
+	g.values = make([]uint64, 0, (g.n+golombSample-1)/golombSample)
+	g.offsets = make([]int, 0, (g.n+golombSample-1)/golombSample)
+	r := bitReader{data: g.data}
+	value := uint64(0)
+	for i := 0; i < g.n; i++ {
+		value += r.readDelta(g.p)
+		if i%golombSample == 0 {
+			g.values = append(g.values, value)
+			g.offsets = append(g.offsets, r.n)
+		}
+	}
+}
+
+// hash return the hash of x in [0, n*2^p)
+func (g *GolombSet) hash(x int) uint64 {
+	hi, _ := bits.Mul64(hashInt(uint64(x)), uint64(g.n)<<uint(g.p))
+	return hi
+}
+
+// bitWriter write bits most significant first
+type bitWriter struct {
+	data []byte
+	n    int // number of bits written
+}
+
+func (w *bitWriter) writeBit(bit uint64) {
+	if w.n%8 == 0 {
+		w.data = append(w.data, 0)
+	}
+	w.data[len(w.data)-1] |= byte(bit) << uint(7-w.n%8)
+	w.n++
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(v >> uint(i) & 1)
+	}
+}
+
+// bitReader read bits written by bitWriter
+// reading past the end return zero bits
+type bitReader struct {
+	data []byte
+	n    int // number of bits read
+}
+
+func (r *bitReader) readBit() uint64 {
+	i := r.n / 8
+	r.n++
+	if i >= len(r.data) {
+		return 0
+	}
+	return uint64(r.data[i]>>uint(7-(r.n-1)%8)) & 1
+}
+
+func (r *bitReader) readBits(n int) uint64 {
+	v := uint64(0)
+	for i := 0; i < n; i++ {
+		v = v<<1 | r.readBit()
+	}
+	return v
+}
+
+// readDelta read a Golomb-Rice coded value with parameter p
+func (r *bitReader) readDelta(p int) uint64 {
+	q := uint64(0)
+	for r.readBit() == 1 {
+		q++
+	}
+	return q<<uint(p) | r.readBits(p)
+}
@@ -0,0 +1,80 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestGolombHas(t *testing.T) {
+	n := bitmap.New()
+	for i := 0; i < 10000; i += 3 {
+		n.Add(i)
+	}
+	g := bitmap.NewGolomb(n, 10)
+	if g.Len() != n.Len() {
+		t.Errorf("TestGolombHas failed. Expected %d, Got %d", n.Len(), g.Len())
+	}
+	xs := make([]int, 10000)
+	for i := range xs {
+		xs[i] = i
+	}
+	found := g.HasMany(xs)
+	fp := 0
+	for i := 0; i < 10000; i++ {
+		if g.Has(i) != found[i] {
+			t.Fatalf("TestGolombHas failed. Expected Has(%d) to be %v", i, found[i])
+		}
+		if found[i] {
+			if i%3 != 0 {
+				fp++
+			}
+		} else if i%3 == 0 {
+			t.Fatalf("TestGolombHas failed. Expected %d in set", i)
+		}
+	}
+	if fp > 20 {
+		t.Errorf("TestGolombHas failed. Expected about 6 false positives, Got %d", fp)
+	}
+	if bits := len(mustMarshal(t, g)) * 8 / g.Len(); bits > 13 {
+		t.Errorf("TestGolombHas failed. Expected about 12 bits per element, Got %d", bits)
+	}
+}
+
+func TestGolombHasMany(t *testing.T) {
+	g := bitmap.NewGolombInts([]int{1, 5, 9, 100, 1000}, 16)
+	xs := []int{0, 1, 2, 5, 9, 10, 100, 999, 1000, 5000}
+	found := g.HasMany(xs)
+	for i, x := range xs {
+		expected := x == 1 || x == 5 || x == 9 || x == 100 || x == 1000
+		if found[i] != expected || g.Has(x) != expected {
+			t.Errorf("TestGolombHasMany failed for %d. Expected %v, Got %v", x, expected, found[i])
+		}
+	}
+	empty := bitmap.NewGolombInts(nil, 8)
+	if empty.Has(1) {
+		t.Errorf("TestGolombHasMany failed. Expected empty set")
+	}
+}
+
+func TestGolombBinary(t *testing.T) {
+	g := bitmap.NewGolombInts([]int{3, 30, 300}, 12)
+	h := &bitmap.GolombSet{}
+	data := mustMarshal(t, g)
+	if err := h.UnmarshalBinary(data); err != nil {
+		t.Fatalf("TestGolombBinary failed. %v", err)
+	}
+	if !h.Has(3) || !h.Has(30) || !h.Has(300) || h.Has(4) || h.Len() != 3 {
+		t.Errorf("TestGolombBinary failed.")
+	}
+	if err := h.UnmarshalBinary(data[:17]); err == nil {
+		t.Errorf("TestGolombBinary failed. Expected error for short data")
+	}
+}
+
+func mustMarshal(t *testing.T, g *bitmap.GolombSet) []byte {
+	data, err := g.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed. %v", err)
+	}
+	return data
+}
@@ -0,0 +1,53 @@
+package bitmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+)
+
+var errShortBuffer = errors.New("bitmap: data too short")
+
+// hashBytes return a 64 bits hash of data
+func hashBytes(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return mix64(h.Sum64())
+}
+
+// hashInt return a 64 bits hash of x
+func hashInt(x uint64) uint64 {
+	return mix64(x + 0x9e3779b97f4a7c15)
+}
+
+// mix64 is the finalizer of splitmix64, it spreads every input bit to all output bits
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// appendWords append words to b as little endian uint64s
+func appendWords(b []byte, words []bitInt) []byte {
+	var buf [8]byte
+	for _, word := range words {
+		binary.LittleEndian.PutUint64(buf[:], uint64(word))
+		b = append(b, buf[:]...)
+	}
+	return b
+}
+
+// readWords read n little endian uint64s from b into words
+func readWords(b []byte, n int) ([]bitInt, error) {
+	if n < 0 || len(b) < n*8 {
+		return nil, errShortBuffer
+	}
+	words := make([]bitInt, n)
+	for i := range words {
+		words[i] = bitInt(binary.LittleEndian.Uint64(b[i*8:]))
+	}
+	return words, nil
+}
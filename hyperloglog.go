@@ -0,0 +1,185 @@
+package bitmap
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	hllMinPrecision = 4
+	hllMaxPrecision = 18
+	hllRegisterSize = 6 // bits of every register
+)
+
+// HyperLogLog estimate the number of distinct items
+// registers are kept in a map while few are set, then promoted to a PackedArray
+type HyperLogLog struct {
+	p      int // precision, there are 2^p registers
+	m      int
+	sparse map[uint32]uint8
+	dense  *PackedArray
+}
+
+// NewHLL return a new HyperLogLog with 2^p registers
+// the standard error is about 1.04 / sqrt(2^p)
+func NewHLL(p int) *HyperLogLog {
+	if p < hllMinPrecision || p > hllMaxPrecision {
+		return nil
+	}
+	return &HyperLogLog{
+		p:      p,
+		m:      1 << uint(p),
+		sparse: make(map[uint32]uint8),
+	}
+}
+
+// Precision return the precision of HyperLogLog
+func (h *HyperLogLog) Precision() int {
+	return h.p
+}
+
+// IsSparse return true if registers are not promoted to dense layout yet
+func (h *HyperLogLog) IsSparse() bool {
+	return h.dense == nil
+}
+
+// Add add data to the HyperLogLog
+func (h *HyperLogLog) Add(data []byte) {
+	h.addHash(hashBytes(data))
+}
+
+// AddString add s to the HyperLogLog
+func (h *HyperLogLog) AddString(s string) {
+	h.addHash(hashBytes([]byte(s)))
+}
+
+// AddInt add x to the HyperLogLog
+func (h *HyperLogLog) AddInt(x int) {
+	h.addHash(hashInt(uint64(x)))
+}
+
+// AddBitmap add every element of n to the HyperLogLog
+// elements are hashed the same as AddInt
+func (h *HyperLogLog) AddBitmap(n *NBitmap) {
+	n.Range(func(x int) bool {
+		h.AddInt(x)
+		return true
+	})
+}
+
+// Estimate return the estimated number of distinct items
+func (h *HyperLogLog) Estimate() uint64 {
+	sum := float64(h.m)
+	zeros := h.m
+	h.rangeRegisters(func(i uint32, rank uint8) {
+		sum += math.Ldexp(1, -int(rank)) - 1
+		zeros--
+	})
+	m := float64(h.m)
+	estimate := hllAlpha(h.m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// linear counting is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Merge h = h | c, registers keep the largest rank
+// h must have the same precision of c
+func (h *HyperLogLog) Merge(c *HyperLogLog) {
+	if h.p != c.p {
+		return
+	}
+	if c.dense != nil {
+		h.promote()
+	}
+	c.rangeRegisters(func(i uint32, rank uint8) {
+		h.setRegister(i, rank)
+	})
+}
+
+// Clear make the HyperLogLog empty
+func (h *HyperLogLog) Clear() {
+	*h = *NewHLL(h.p)
+}
+
+// Copy return a copy HyperLogLog
+func (h *HyperLogLog) Copy() *HyperLogLog {
+	new := HyperLogLog{}
+	new.p = h.p
+	new.m = h.m
+	if h.dense != nil {
+		new.dense = h.dense.Copy()
+		return &new
+	}
+	new.sparse = make(map[uint32]uint8, len(h.sparse))
+	for i, rank := range h.sparse {
+		new.sparse[i] = rank
+	}
+	return &new
+}
+
+// addHash add a hashed item
+// the top p bits select the register, the rank is the position of the first 1 bit in the rest
+func (h *HyperLogLog) addHash(hash uint64) {
+	i := uint32(hash >> uint(64-h.p))
+	rank := uint8(bits.LeadingZeros64(hash<<uint(h.p)|1<<uint(h.p-1)) + 1)
+	h.setRegister(i, rank)
+}
+
+// setRegister raise register i to rank
+func (h *HyperLogLog) setRegister(i uint32, rank uint8) {
+	if h.dense != nil {
+		if uint64(rank) > h.dense.Get(int(i)) {
+			h.dense.Set(int(i), uint64(rank))
+		}
+		return
+	}
+	if rank > h.sparse[i] {
+		h.sparse[i] = rank
+		// a map entry cost more than 8 bytes while a register cost 6 bits
+		if len(h.sparse) > h.m*hllRegisterSize/64 {
+			h.promote()
+		}
+	}
+}
+
+// promote move registers from the sparse map to a PackedArray
+func (h *HyperLogLog) promote() {
+	if h.dense != nil {
+		return
+	}
+	h.dense = NewPacked(hllRegisterSize, h.m)
+	for i, rank := range h.sparse {
+		h.dense.Set(int(i), uint64(rank))
+	}
+	h.sparse = nil
+}
+
+// rangeRegisters call f with every nonzero register
+func (h *HyperLogLog) rangeRegisters(f func(i uint32, rank uint8)) {
+	if h.dense == nil {
+		for i, rank := range h.sparse {
+			f(i, rank)
+		}
+		return
+	}
+	for i, rank := range h.dense.Uint32s(make([]uint32, 0, h.m)) {
+		if rank != 0 {
+			f(uint32(i), uint8(rank))
+		}
+	}
+}
+
+// hllAlpha return the bias correction constant for m registers
+func hllAlpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(m))
+}
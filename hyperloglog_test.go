@@ -0,0 +1,93 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestHLLEstimate(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		h := bitmap.NewHLL(14)
+		for i := 0; i < n; i++ {
+			h.AddString(strconv.Itoa(i))
+			h.AddString(strconv.Itoa(i))
+		}
+		if e := math.Abs(float64(h.Estimate())-float64(n)) / float64(n); e > 0.03 {
+			t.Errorf("TestHLLEstimate failed. Expected about %d, Got %d", n, h.Estimate())
+		}
+	}
+	if bitmap.NewHLL(3) != nil || bitmap.NewHLL(19) != nil {
+		t.Errorf("TestHLLEstimate failed. Expected nil for invalid precision")
+	}
+}
+
+func TestHLLSparse(t *testing.T) {
+	h := bitmap.NewHLL(12)
+	for i := 0; i < 100; i++ {
+		h.AddInt(i)
+	}
+	if !h.IsSparse() {
+		t.Errorf("TestHLLSparse failed. Expected sparse")
+	}
+	sparse := h.Estimate()
+	d := h.Copy()
+	for i := 0; i < 100000; i++ {
+		d.AddInt(i)
+	}
+	if d.IsSparse() || !h.IsSparse() {
+		t.Errorf("TestHLLSparse failed. Expected dense")
+	}
+	if sparse < 95 || sparse > 105 {
+		t.Errorf("TestHLLSparse failed. Expected about 100, Got %d", sparse)
+	}
+}
+
+func TestHLLMerge(t *testing.T) {
+	a := bitmap.NewHLL(14)
+	b := bitmap.NewHLL(14)
+	for i := 0; i < 50000; i++ {
+		a.AddInt(i)
+	}
+	for i := 25000; i < 75000; i++ {
+		b.AddInt(i)
+	}
+	s := bitmap.NewHLL(14)
+	s.AddInt(1)
+	s.Merge(a)
+	s.Merge(b)
+	if e := math.Abs(float64(s.Estimate())-75000) / 75000; e > 0.03 {
+		t.Errorf("TestHLLMerge failed. Expected about 75000, Got %d", s.Estimate())
+	}
+	s.Clear()
+	if s.Estimate() != 0 || !s.IsSparse() {
+		t.Errorf("TestHLLMerge Clear failed.")
+	}
+}
+
+func TestHLLBitmap(t *testing.T) {
+	n := bitmap.New()
+	for i := 0; i < 20000; i += 2 {
+		n.Add(i)
+	}
+	h := bitmap.NewHLL(14)
+	h.AddBitmap(n)
+	if e := math.Abs(float64(h.Estimate())-10000) / 10000; e > 0.03 {
+		t.Errorf("TestHLLBitmap failed. Expected about 10000, Got %d", h.Estimate())
+	}
+	g := bitmap.NewHLL(14)
+	g.AddInt(0)
+	g.AddInt(2)
+	g.Merge(h)
+	if g.Estimate() != h.Estimate() {
+		t.Errorf("TestHLLBitmap failed. Expected %d, Got %d", h.Estimate(), g.Estimate())
+	}
+}
+
+func BenchmarkHLL(b *testing.B) {
+	h := bitmap.NewHLL(14)
+	for i := 0; i < b.N; i++ {
+		h.AddInt(i)
+	}
+}
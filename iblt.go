@@ -0,0 +1,226 @@
+package bitmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// ErrDecode is returned when an IBLT has too many differences to be decoded
+var ErrDecode = errors.New("bitmap: IBLT can not be fully decoded")
+
+// ibltCell is a cell of IBLT
+type ibltCell struct {
+	count   int64
+	keySum  uint64 // xor of keys
+	hashSum uint64 // xor of key checksums
+}
+
+// IBLT is an invertible bloom lookup table of integers
+// the IBLT of two sets subtracted from each other can be decoded into their differences,
+// as long as there are not too many of them
+type IBLT struct {
+	m     int // number of cells
+	k     int // number of hash functions
+	cells []ibltCell
+}
+
+// NewIBLT return a new IBLT of m cells and k hash functions
+// about 1.5 cells per expected difference with k = 3 is usually enough
+func NewIBLT(m int, k int) *IBLT {
+	if k <= 0 || m < k {
+		return nil
+	}
+	return &IBLT{
+		m:     m,
+		k:     k,
+		cells: make([]ibltCell, m),
+	}
+}
+
+// Size return number of cells in IBLT
+func (t *IBLT) Size() int {
+	return t.m
+}
+
+// Hashes return number of hash functions used by IBLT
+func (t *IBLT) Hashes() int {
+	return t.k
+}
+
+// Add add x to the IBLT
+func (t *IBLT) Add(x int) {
+	if x < 0 {
+		return
+	}
+	t.update(uint64(x), 1)
+}
+
+// Remove remove x in the IBLT
+func (t *IBLT) Remove(x int) {
+	if x < 0 {
+		return
+	}
+	t.update(uint64(x), -1)
+}
+
+// AddBitmap add every element of n to the IBLT
+func (t *IBLT) AddBitmap(n *NBitmap) {
+	n.Range(func(x int) bool {
+		t.update(uint64(x), 1)
+		return true
+	})
+}
+
+// Subtract t = t - c
+// the result decodes into elements only in t and elements only in c
+// t must have the same size and hashes of c
+func (t *IBLT) Subtract(c *IBLT) {
+	if t.m != c.m || t.k != c.k {
+		return
+	}
+	for i := range t.cells {
+		t.cells[i].count -= c.cells[i].count
+		t.cells[i].keySum ^= c.cells[i].keySum
+		t.cells[i].hashSum ^= c.cells[i].hashSum
+	}
+}
+
+// Decode list elements added more than removed and elements removed more than added
+// for an IBLT subtracted by another, they are elements only in each side
+// elements are in increasing order, ErrDecode is returned with the partial result
+// if the IBLT has too many differences or is corrupted; t is not modified
+func (t *IBLT) Decode() ([]int, []int, error) {
+	cells := make([]ibltCell, len(t.cells))
+	copy(cells, t.cells)
+	var added, removed []int
+	pure := make([]int, 0, len(cells))
+	for i := range cells {
+		if t.isPure(cells[i]) {
+			pure = append(pure, i)
+		}
+	}
+	for len(pure) > 0 {
+		// every element empty a cell, more elements than cells mean corrupted cells
+		if len(added)+len(removed) >= len(cells) {
+			sort.Ints(added)
+			sort.Ints(removed)
+			return added, removed, ErrDecode
+		}
+		i := pure[len(pure)-1]
+		pure = pure[:len(pure)-1]
+		cell := cells[i]
+		if !t.isPure(cell) {
+			continue
+		}
+		key := cell.keySum
+		if cell.count == 1 {
+			added = append(added, int(key))
+		} else {
+			removed = append(removed, int(key))
+		}
+		check := ibltCheck(key)
+		for _, j := range t.indexes(key) {
+			cells[j].count -= cell.count
+			cells[j].keySum ^= key
+			cells[j].hashSum ^= check
+			if t.isPure(cells[j]) {
+				pure = append(pure, j)
+			}
+		}
+	}
+	sort.Ints(added)
+	sort.Ints(removed)
+	for _, cell := range cells {
+		if cell != (ibltCell{}) {
+			return added, removed, ErrDecode
+		}
+	}
+	return added, removed, nil
+}
+
+// Clear make the IBLT empty
+func (t *IBLT) Clear() {
+	*t = *NewIBLT(t.m, t.k)
+}
+
+// Copy return a copy IBLT
+func (t *IBLT) Copy() *IBLT {
+	new := IBLT{}
+	new.m = t.m
+	new.k = t.k
+	new.cells = make([]ibltCell, len(t.cells))
+	copy(new.cells, t.cells)
+	return &new
+}
+
+// MarshalBinary encode the IBLT as size and hashes as little endian uint64,
+// then count, key sum and checksum sum of every cell as little endian uint64
+func (t *IBLT) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 16+24*len(t.cells))
+	binary.LittleEndian.PutUint64(b, uint64(t.m))
+	binary.LittleEndian.PutUint64(b[8:], uint64(t.k))
+	for i, cell := range t.cells {
+		c := b[16+24*i:]
+		binary.LittleEndian.PutUint64(c, uint64(cell.count))
+		binary.LittleEndian.PutUint64(c[8:], cell.keySum)
+		binary.LittleEndian.PutUint64(c[16:], cell.hashSum)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decode data encoded by MarshalBinary into the IBLT
+func (t *IBLT) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return errShortBuffer
+	}
+	m := binary.LittleEndian.Uint64(data)
+	k := binary.LittleEndian.Uint64(data[8:])
+	if k == 0 || m < k || m > uint64(len(data)) {
+		return errors.New("bitmap: invalid IBLT header")
+	}
+	if uint64(len(data)-16) < 24*m {
+		return errShortBuffer
+	}
+	new := NewIBLT(int(m), int(k))
+	for i := range new.cells {
+		c := data[16+24*i:]
+		new.cells[i].count = int64(binary.LittleEndian.Uint64(c))
+		new.cells[i].keySum = binary.LittleEndian.Uint64(c[8:])
+		new.cells[i].hashSum = binary.LittleEndian.Uint64(c[16:])
+	}
+	*t = *new
+	return nil
+}
+
+// update add key to the IBLT count times
+func (t *IBLT) update(key uint64, count int64) {
+	check := ibltCheck(key)
+	for _, i := range t.indexes(key) {
+		t.cells[i].count += count
+		t.cells[i].keySum ^= key
+		t.cells[i].hashSum ^= check
+	}
+}
+
+// indexes return the cells of key, one in each of k partitions
+// so a key never hits the same cell twice
+func (t *IBLT) indexes(key uint64) []int {
+	part := uint64(t.m / t.k)
+	h := hashInt(key)
+	indexes := make([]int, t.k)
+	for i := range indexes {
+		indexes[i] = i*int(part) + int(mix64(h+uint64(i))%part)
+	}
+	return indexes
+}
+
+// isPure return true if cell hold exactly one key
+func (t *IBLT) isPure(cell ibltCell) bool {
+	return (cell.count == 1 || cell.count == -1) && cell.hashSum == ibltCheck(cell.keySum)
+}
+
+// ibltCheck return the checksum of key
+func ibltCheck(key uint64) uint64 {
+	return mix64(key ^ 0x5851f42d4c957f2d)
+}
@@ -0,0 +1,100 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestIBLTDecode(t *testing.T) {
+	a := bitmap.New()
+	b := bitmap.New()
+	for i := 0; i < 100000; i++ {
+		a.Add(i)
+		b.Add(i)
+	}
+	a.Remove(10)
+	a.Remove(99999)
+	a.Add(200000)
+	b.Remove(500)
+	b.Add(123456)
+	b.Add(300000)
+	ta := bitmap.NewIBLT(30, 3)
+	tb := bitmap.NewIBLT(30, 3)
+	ta.AddBitmap(a)
+	tb.AddBitmap(b)
+	ta.Subtract(tb)
+	onlyA, onlyB, err := ta.Decode()
+	if err != nil {
+		t.Fatalf("TestIBLTDecode failed. %v", err)
+	}
+	if len(onlyA) != 2 || onlyA[0] != 500 || onlyA[1] != 200000 {
+		t.Errorf("TestIBLTDecode failed. Expected [500 200000], Got %v", onlyA)
+	}
+	if len(onlyB) != 4 || onlyB[0] != 10 || onlyB[1] != 99999 || onlyB[2] != 123456 || onlyB[3] != 300000 {
+		t.Errorf("TestIBLTDecode failed. Expected [10 99999 123456 300000], Got %v", onlyB)
+	}
+}
+
+func TestIBLTTooMany(t *testing.T) {
+	ta := bitmap.NewIBLT(12, 3)
+	for i := 0; i < 100; i++ {
+		ta.Add(i)
+	}
+	if _, _, err := ta.Decode(); err != bitmap.ErrDecode {
+		t.Errorf("TestIBLTTooMany failed. Expected ErrDecode, Got %v", err)
+	}
+	for i := 0; i < 98; i++ {
+		ta.Remove(i)
+	}
+	added, _, err := ta.Decode()
+	if err != nil || len(added) != 2 || added[0] != 98 || added[1] != 99 {
+		t.Errorf("TestIBLTTooMany failed. Expected [98 99], Got %v %v", added, err)
+	}
+}
+
+func TestIBLTCorrupted(t *testing.T) {
+	ta := bitmap.NewIBLT(6, 3)
+	ta.Add(7)
+	data, _ := ta.MarshalBinary()
+	// only the first cell of 7 is kept, peeling it fill the other cells of 7
+	// and peeling them fill the first cell again
+	first := true
+	for i := 16; i < len(data); i += 24 {
+		if data[i] == 1 && first {
+			first = false
+			continue
+		}
+		copy(data[i:i+24], make([]byte, 24))
+	}
+	if err := ta.UnmarshalBinary(data); err != nil {
+		t.Fatalf("TestIBLTCorrupted failed. %v", err)
+	}
+	added, removed, err := ta.Decode()
+	if err != bitmap.ErrDecode || len(added)+len(removed) > ta.Size() {
+		t.Errorf("TestIBLTCorrupted failed. Expected ErrDecode, Got %v %v %v", added, removed, err)
+	}
+}
+
+func TestIBLTBinary(t *testing.T) {
+	ta := bitmap.NewIBLT(30, 3)
+	ta.Add(1)
+	ta.Add(2)
+	data, err := ta.MarshalBinary()
+	if err != nil {
+		t.Fatalf("TestIBLTBinary failed. %v", err)
+	}
+	tb := &bitmap.IBLT{}
+	if err := tb.UnmarshalBinary(data); err != nil {
+		t.Fatalf("TestIBLTBinary failed. %v", err)
+	}
+	tc := bitmap.NewIBLT(30, 3)
+	tc.Add(2)
+	tb.Subtract(tc)
+	added, removed, err := tb.Decode()
+	if err != nil || len(added) != 1 || added[0] != 1 || len(removed) != 0 {
+		t.Errorf("TestIBLTBinary failed. Expected [1], Got %v %v %v", added, removed, err)
+	}
+	if err := tb.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("TestIBLTBinary failed. Expected error for short data")
+	}
+}
@@ -0,0 +1,144 @@
+package bitmap
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Int64Bitmap is a bitSet of all int64 numbers
+// it's a Roaring64 of the numbers with the sign bit flipped, so the
+// negative half is ordered before the positive half, and sparse numbers
+// like timestamps are kept in small sorted arrays instead of pages
+type Int64Bitmap struct {
+	len  int
+	bits Roaring64 // numbers with the sign bit flipped
+}
+
+// NewInt64 return a new empty Int64Bitmap
+func NewInt64() *Int64Bitmap {
+	return &Int64Bitmap{
+		len: 0,
+	}
+}
+
+// int64Key return x with the sign bit flipped
+func int64Key(x int64) uint64 {
+	return uint64(x) ^ 1<<63
+}
+
+// int64Value return the number of key
+func int64Value(key uint64) int64 {
+	return int64(key ^ 1<<63)
+}
+
+// String return formated string of bitmap
+func (b *Int64Bitmap) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	b.Range(func(x int64) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers in bitmap
+func (b *Int64Bitmap) Len() int {
+	return b.len
+}
+
+// Has return true if x is in the bitmap
+func (b *Int64Bitmap) Has(x int64) bool {
+	return b.bits.Has(int64Key(x))
+}
+
+// Add add x to the bitmap
+func (b *Int64Bitmap) Add(x int64) {
+	if b.bits.add(int64Key(x)) {
+		b.len++
+	}
+}
+
+// Remove remove x in bitmap
+func (b *Int64Bitmap) Remove(x int64) {
+	if b.bits.remove(int64Key(x)) {
+		b.len--
+	}
+}
+
+// Clear make the bitmap empty
+func (b *Int64Bitmap) Clear() {
+	*b = *NewInt64()
+}
+
+// Copy return a copy bitmap
+func (b *Int64Bitmap) Copy() *Int64Bitmap {
+	new := Int64Bitmap{}
+	new.len = b.len
+	new.bits = *b.bits.Copy()
+	return &new
+}
+
+// Range call f with every element in increasing signed order
+// stop if f return false
+func (b *Int64Bitmap) Range(f func(x int64) bool) {
+	b.bits.Range(func(key uint64) bool {
+		return f(int64Value(key))
+	})
+}
+
+// Min return the smallest element, false if bitmap is empty
+func (b *Int64Bitmap) Min() (int64, bool) {
+	if b.len == 0 {
+		return 0, false
+	}
+	key, _ := b.bits.Select(0)
+	return int64Value(key), true
+}
+
+// Max return the largest element, false if bitmap is empty
+func (b *Int64Bitmap) Max() (int64, bool) {
+	if b.len == 0 {
+		return 0, false
+	}
+	last := b.bits.buckets[len(b.bits.buckets)-1]
+	low, _ := last.selectAt(last.card() - 1)
+	return int64Value(uint64(b.bits.keys[len(b.bits.keys)-1])<<32 | uint64(low)), true
+}
+
+// Union b = b | c
+// elements in b or c
+func (b *Int64Bitmap) Union(c *Int64Bitmap) {
+	b.bits.Union(&c.bits)
+	b.recount()
+}
+
+// Intersect b = b & c
+// elements both in b and c
+func (b *Int64Bitmap) Intersect(c *Int64Bitmap) {
+	b.bits.Intersect(&c.bits)
+	b.recount()
+}
+
+// Except b = b - c
+// elements only in b
+func (b *Int64Bitmap) Except(c *Int64Bitmap) {
+	b.bits.Except(&c.bits)
+	b.recount()
+}
+
+// SymExcept b = (b - c) | (c - b)
+// elements only in b or only in c
+func (b *Int64Bitmap) SymExcept(c *Int64Bitmap) {
+	b.bits.SymExcept(&c.bits)
+	b.recount()
+}
+
+// recount count numbers after a set operation
+func (b *Int64Bitmap) recount() {
+	b.len = int(b.bits.Len())
+}
@@ -0,0 +1,111 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"math"
+	"testing"
+)
+
+func TestInt64(t *testing.T) {
+	b := bitmap.NewInt64()
+	if _, ok := b.Min(); ok {
+		t.Errorf("TestInt64 Min failed. Expected false for empty bitmap")
+	}
+	for _, x := range []int64{5, -3, math.MaxInt64, math.MinInt64, 0, -1, 1 << 40, 5} {
+		b.Add(x)
+	}
+	expected := "{-9223372036854775808 -3 -1 0 5 1099511627776 9223372036854775807}"
+	if b.String() != expected || b.Len() != 7 {
+		t.Errorf("TestInt64 failed. Expected %s, Got %s", expected, b.String())
+	}
+	if !b.Has(-3) || b.Has(3) || !b.Has(math.MinInt64) {
+		t.Errorf("TestInt64 Has failed. Got %s", b.String())
+	}
+	if min, _ := b.Min(); min != math.MinInt64 {
+		t.Errorf("TestInt64 Min failed. Expected %d, Got %d", int64(math.MinInt64), min)
+	}
+	if max, _ := b.Max(); max != math.MaxInt64 {
+		t.Errorf("TestInt64 Max failed. Expected %d, Got %d", int64(math.MaxInt64), max)
+	}
+	b.Remove(math.MinInt64)
+	b.Remove(math.MaxInt64)
+	b.Remove(7)
+	if min, _ := b.Min(); min != -3 || b.Len() != 5 {
+		t.Errorf("TestInt64 Remove failed. Expected -3, Got %d", min)
+	}
+	if max, _ := b.Max(); max != 1<<40 {
+		t.Errorf("TestInt64 Remove failed. Expected %d, Got %d", int64(1<<40), max)
+	}
+	b.Clear()
+	if b.Len() != 0 || b.String() != "{}" {
+		t.Errorf("TestInt64 Clear failed. Got %s", b.String())
+	}
+}
+
+func TestInt64Sets(t *testing.T) {
+	b := bitmap.NewInt64()
+	c := bitmap.NewInt64()
+	for _, x := range []int64{-10000, -2, 0, 1, 2, 1 << 50} {
+		b.Add(x)
+	}
+	for _, x := range []int64{-10000, -1, 2, 5, 1 << 50} {
+		c.Add(x)
+	}
+	cases := []struct {
+		name     string
+		op       func(b, c *bitmap.Int64Bitmap)
+		expected string
+	}{
+		{"Union", (*bitmap.Int64Bitmap).Union, "{-10000 -2 -1 0 1 2 5 1125899906842624}"},
+		{"Intersect", (*bitmap.Int64Bitmap).Intersect, "{-10000 2 1125899906842624}"},
+		{"Except", (*bitmap.Int64Bitmap).Except, "{-2 0 1}"},
+		{"SymExcept", (*bitmap.Int64Bitmap).SymExcept, "{-2 -1 0 1 5}"},
+	}
+	for _, tc := range cases {
+		bb := b.Copy()
+		tc.op(bb, c)
+		if bb.String() != tc.expected {
+			t.Errorf("TestInt64Sets %s failed. Expected %s, Got %s", tc.name, tc.expected, bb.String())
+		}
+	}
+	b.SymExcept(b.Copy())
+	if b.Len() != 0 || b.String() != "{}" {
+		t.Errorf("TestInt64Sets SymExcept failed. Expected {}, Got %s", b.String())
+	}
+}
+
+func TestInt64Sparse(t *testing.T) {
+	// timestamps in nanoseconds, one every 10 seconds, every one in its own 4096 range
+	b := bitmap.NewInt64()
+	const start, step = int64(1700000000) * 1e9, int64(10) * 1e9
+	for i := int64(0); i < 10000; i++ {
+		b.Add(start + i*step)
+		b.Add(-start - i*step)
+	}
+	if b.Len() != 20000 || !b.Has(start+500*step) || b.Has(start+500*step+1) || !b.Has(-start) {
+		t.Errorf("TestInt64Sparse failed. Expected 20000 elements, Got %d", b.Len())
+	}
+	min, _ := b.Min()
+	max, _ := b.Max()
+	if min != -start-9999*step || max != start+9999*step {
+		t.Errorf("TestInt64Sparse failed. Expected %d %d, Got %d %d", -start-9999*step, start+9999*step, min, max)
+	}
+	prev, n := int64(math.MinInt64), 0
+	b.Range(func(x int64) bool {
+		if x <= prev && n > 0 {
+			t.Fatalf("TestInt64Sparse Range failed. %d after %d", x, prev)
+		}
+		prev, n = x, n+1
+		return true
+	})
+	c := b.Copy()
+	c.Remove(start)
+	b.Intersect(c)
+	if b.Len() != 19999 || b.Has(start) || n != 20000 {
+		t.Errorf("TestInt64Sparse Intersect failed. Expected 19999 elements, Got %d", b.Len())
+	}
+	b.SymExcept(b)
+	if b.Len() != 0 {
+		t.Errorf("TestInt64Sparse SymExcept failed. Expected {}, Got %d elements", b.Len())
+	}
+}
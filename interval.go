@@ -0,0 +1,294 @@
+package bitmap
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// interval is the run [lo, hi)
+type interval struct {
+	lo, hi int
+}
+
+// IntervalSet is a set of integers stored as sorted disjoint runs [lo, hi)
+// it's suitable for long runs of consecutive integers
+// elements must be less than math.MaxInt, hi of its run would overflow
+type IntervalSet struct {
+	len  int
+	runs []interval
+}
+
+// NewInterval return a new empty IntervalSet
+func NewInterval() *IntervalSet {
+	return &IntervalSet{}
+}
+
+// NewIntervalFromBitmap return a new IntervalSet of elements in n
+// math.MaxInt is not copied
+func NewIntervalFromBitmap(n *NBitmap) *IntervalSet {
+	s := NewInterval()
+	n.Range(func(x int) bool {
+		if x < math.MaxInt {
+			s.appendRun(x, x+1)
+		}
+		return true
+	})
+	return s
+}
+
+// NewIntervalFromRBitmap return a new IntervalSet of elements in r
+// math.MaxInt is not copied
+func NewIntervalFromRBitmap(r *RBitmap) *IntervalSet {
+	s := NewInterval()
+	r.Range(func(x int) bool {
+		if x < math.MaxInt {
+			s.appendRun(x, x+1)
+		}
+		return true
+	})
+	return s
+}
+
+// String return formated string of set, runs are printed as first-last
+func (s *IntervalSet) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, run := range s.runs {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		if run.hi-run.lo == 1 {
+			fmt.Fprintf(&buf, "%d", run.lo)
+		} else {
+			fmt.Fprintf(&buf, "%d-%d", run.lo, run.hi-1)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers in set
+func (s *IntervalSet) Len() int {
+	return s.len
+}
+
+// Runs return numbers of runs in set
+func (s *IntervalSet) Runs() int {
+	return len(s.runs)
+}
+
+// Has return true if x is in the set
+func (s *IntervalSet) Has(x int) bool {
+	i := sort.Search(len(s.runs), func(i int) bool { return s.runs[i].hi > x })
+	return i < len(s.runs) && s.runs[i].lo <= x
+}
+
+// Add add x to the set
+// x is ignored if it's math.MaxInt
+func (s *IntervalSet) Add(x int) {
+	if x == math.MaxInt {
+		return
+	}
+	s.AddRange(x, x+1)
+}
+
+// AddRange add [lo, hi) to the set
+func (s *IntervalSet) AddRange(lo int, hi int) {
+	if lo >= hi {
+		return
+	}
+	// runs from i to j-1 overlap or touch [lo, hi)
+	i := sort.Search(len(s.runs), func(i int) bool { return s.runs[i].hi >= lo })
+	j := i
+	for j < len(s.runs) && s.runs[j].lo <= hi {
+		if s.runs[j].lo < lo {
+			lo = s.runs[j].lo
+		}
+		if s.runs[j].hi > hi {
+			hi = s.runs[j].hi
+		}
+		s.len -= s.runs[j].hi - s.runs[j].lo
+		j++
+	}
+	s.len += hi - lo
+	s.replace(i, j, interval{lo, hi})
+}
+
+// Remove remove x in set
+func (s *IntervalSet) Remove(x int) {
+	if x == math.MaxInt {
+		return
+	}
+	s.RemoveRange(x, x+1)
+}
+
+// RemoveRange remove [lo, hi) in set
+func (s *IntervalSet) RemoveRange(lo int, hi int) {
+	if lo >= hi {
+		return
+	}
+	// runs from i to j-1 overlap [lo, hi)
+	i := sort.Search(len(s.runs), func(i int) bool { return s.runs[i].hi > lo })
+	j := i
+	for j < len(s.runs) && s.runs[j].lo < hi {
+		j++
+	}
+	if i == j {
+		return
+	}
+	var keep []interval
+	if first := s.runs[i]; first.lo < lo {
+		keep = append(keep, interval{first.lo, lo})
+	}
+	if last := s.runs[j-1]; last.hi > hi {
+		keep = append(keep, interval{hi, last.hi})
+	}
+	for _, run := range s.runs[i:j] {
+		s.len -= run.hi - run.lo
+	}
+	for _, run := range keep {
+		s.len += run.hi - run.lo
+	}
+	s.replace(i, j, keep...)
+}
+
+// Range call f with every run [lo, hi) in increasing order
+// stop if f return false
+func (s *IntervalSet) Range(f func(lo int, hi int) bool) {
+	for _, run := range s.runs {
+		if !f(run.lo, run.hi) {
+			return
+		}
+	}
+}
+
+// Bitmap return a NBitmap of elements, negative elements are dropped
+func (s *IntervalSet) Bitmap() *NBitmap {
+	n := New()
+	for _, run := range s.runs {
+		for x := run.lo; x < run.hi; x++ {
+			n.Add(x)
+		}
+	}
+	return n
+}
+
+// RBitmap return a RBitmap count in [start, end) of elements
+// elements out of the range are dropped
+func (s *IntervalSet) RBitmap(start int, end int) *RBitmap {
+	r := NewR(start, end)
+	if r == nil {
+		return nil
+	}
+	for _, run := range s.runs {
+		x := run.lo
+		if x < start {
+			x = start
+		}
+		for ; x < run.hi && x < end; x++ {
+			r.Add(x)
+		}
+	}
+	return r
+}
+
+// Union s = s | c
+// elements in s or c
+func (s *IntervalSet) Union(c *IntervalSet) {
+	s.merge(c, func(a, b bool) bool { return a || b })
+}
+
+// Intersect s = s & c
+// elements both in s and c
+func (s *IntervalSet) Intersect(c *IntervalSet) {
+	s.merge(c, func(a, b bool) bool { return a && b })
+}
+
+// Except s = s - c
+// elements only in s
+func (s *IntervalSet) Except(c *IntervalSet) {
+	s.merge(c, func(a, b bool) bool { return a && !b })
+}
+
+// SymExcept s = (s - c) | (c - s)
+// elements only in s or only in c
+func (s *IntervalSet) SymExcept(c *IntervalSet) {
+	s.merge(c, func(a, b bool) bool { return a != b })
+}
+
+// Clear make the set empty
+func (s *IntervalSet) Clear() {
+	*s = *NewInterval()
+}
+
+// Copy return a copy set
+func (s *IntervalSet) Copy() *IntervalSet {
+	new := IntervalSet{}
+	new.len = s.len
+	new.runs = make([]interval, len(s.runs))
+	copy(new.runs, s.runs)
+	return &new
+}
+
+// appendRun add [lo, hi) after all runs, lo must not be less than the end of last run
+func (s *IntervalSet) appendRun(lo int, hi int) {
+	if n := len(s.runs); n > 0 && s.runs[n-1].hi == lo {
+		s.runs[n-1].hi = hi
+	} else {
+		s.runs = append(s.runs, interval{lo, hi})
+	}
+	s.len += hi - lo
+}
+
+// replace replace runs from i to j-1 with runs
+func (s *IntervalSet) replace(i int, j int, runs ...interval) {
+	tail := append(runs, s.runs[j:]...)
+	s.runs = append(s.runs[:i], tail...)
+}
+
+// merge s = op(s, c), op tell whether an element is kept from its membership of s and c
+// boundaries of both sets are swept in order
+func (s *IntervalSet) merge(c *IntervalSet, op func(a, b bool) bool) {
+	result := IntervalSet{}
+	i, j := 0, 0
+	inA, inB := false, false
+	start, in := 0, false
+	for i < 2*len(s.runs) || j < 2*len(c.runs) {
+		// the next boundary, every run has a start and an end boundary
+		x := 0
+		takeA := j >= 2*len(c.runs) || (i < 2*len(s.runs) && boundary(s.runs, i) <= boundary(c.runs, j))
+		takeB := i >= 2*len(s.runs) || (j < 2*len(c.runs) && boundary(c.runs, j) <= boundary(s.runs, i))
+		if takeA {
+			x = boundary(s.runs, i)
+		} else {
+			x = boundary(c.runs, j)
+		}
+		for takeA && i < 2*len(s.runs) && boundary(s.runs, i) == x {
+			inA = i%2 == 0
+			i++
+		}
+		for takeB && j < 2*len(c.runs) && boundary(c.runs, j) == x {
+			inB = j%2 == 0
+			j++
+		}
+		if now := op(inA, inB); now != in {
+			if now {
+				start = x
+			} else {
+				result.appendRun(start, x)
+			}
+			in = now
+		}
+	}
+	*s = result
+}
+
+// boundary return the i-th boundary of runs, lo of run i/2 if i is even, otherwise hi
+func boundary(runs []interval, i int) int {
+	if i%2 == 0 {
+		return runs[i/2].lo
+	}
+	return runs[i/2].hi
+}
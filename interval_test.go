@@ -0,0 +1,99 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"math"
+	"testing"
+)
+
+func TestIntervalAdd(t *testing.T) {
+	s := bitmap.NewInterval()
+	s.AddRange(12, 21)
+	s.Add(9)
+	s.AddRange(1, 4)
+	s.Add(5)
+	s.Add(4)
+	s.Add(15)
+	if s.String() != "{1-5 9 12-20}" || s.Len() != 15 || s.Runs() != 3 {
+		t.Errorf("TestIntervalAdd failed. Expected {1-5 9 12-20}, Got %s %d", s.String(), s.Len())
+	}
+	s.AddRange(6, 12)
+	if s.String() != "{1-20}" || s.Len() != 20 {
+		t.Errorf("TestIntervalAdd failed. Expected {1-20}, Got %s", s.String())
+	}
+	if !s.Has(1) || !s.Has(20) || s.Has(0) || s.Has(21) {
+		t.Errorf("TestIntervalAdd Has failed.")
+	}
+	// math.MaxInt+1 overflows the end of its run, it's not added
+	s.Add(math.MaxInt)
+	s.Add(math.MaxInt - 1)
+	if s.Has(math.MaxInt) || !s.Has(math.MaxInt-1) || s.Len() != 21 || s.Runs() != 2 {
+		t.Errorf("TestIntervalAdd MaxInt failed. Expected {1-20 %d}, Got %s", math.MaxInt-1, s.String())
+	}
+}
+
+func TestIntervalRemove(t *testing.T) {
+	s := bitmap.NewInterval()
+	s.AddRange(-10, 100)
+	s.Remove(0)
+	s.RemoveRange(50, 60)
+	s.RemoveRange(90, 200)
+	s.Remove(1000)
+	if s.String() != "{-10--1 1-49 60-89}" || s.Len() != 89 {
+		t.Errorf("TestIntervalRemove failed. Expected {-10--1 1-49 60-89}, Got %s %d", s.String(), s.Len())
+	}
+	s.RemoveRange(-100, 100)
+	if s.String() != "{}" || s.Len() != 0 {
+		t.Errorf("TestIntervalRemove failed. Expected {}, Got %s", s.String())
+	}
+}
+
+func TestIntervalSets(t *testing.T) {
+	a := bitmap.NewInterval()
+	a.AddRange(0, 10)
+	a.AddRange(20, 30)
+	b := bitmap.NewInterval()
+	b.AddRange(5, 25)
+	b.Add(40)
+	s := a.Copy()
+	s.Union(b)
+	if s.String() != "{0-29 40}" || s.Len() != 31 {
+		t.Errorf("TestIntervalSets Union failed. Expected {0-29 40}, Got %s", s.String())
+	}
+	s = a.Copy()
+	s.Intersect(b)
+	if s.String() != "{5-9 20-24}" || s.Len() != 10 {
+		t.Errorf("TestIntervalSets Intersect failed. Expected {5-9 20-24}, Got %s", s.String())
+	}
+	s = a.Copy()
+	s.Except(b)
+	if s.String() != "{0-4 25-29}" {
+		t.Errorf("TestIntervalSets Except failed. Expected {0-4 25-29}, Got %s", s.String())
+	}
+	s = a.Copy()
+	s.SymExcept(b)
+	if s.String() != "{0-4 10-19 25-29 40}" || s.Len() != 21 {
+		t.Errorf("TestIntervalSets SymExcept failed. Expected {0-4 10-19 25-29 40}, Got %s", s.String())
+	}
+}
+
+func TestIntervalBitmap(t *testing.T) {
+	n := bitmap.New()
+	for _, x := range []int{1, 2, 3, 4, 5, 9, 12, 13, 14, 15, 16, 17, 18, 19, 20} {
+		n.Add(x)
+	}
+	s := bitmap.NewIntervalFromBitmap(n)
+	if s.String() != "{1-5 9 12-20}" {
+		t.Errorf("TestIntervalBitmap failed. Expected {1-5 9 12-20}, Got %s", s.String())
+	}
+	if s.Bitmap().String() != n.String() {
+		t.Errorf("TestIntervalBitmap failed. Expected %s, Got %s", n.String(), s.Bitmap().String())
+	}
+	r := s.RBitmap(3, 15)
+	if r.String() != "{3 4 5 9 12 13 14}" {
+		t.Errorf("TestIntervalBitmap RBitmap failed. Expected {3 4 5 9 12 13 14}, Got %s", r.String())
+	}
+	if s := bitmap.NewIntervalFromRBitmap(r); s.String() != "{3-5 9 12-14}" {
+		t.Errorf("TestIntervalBitmap RBitmap failed. Expected {3-5 9 12-14}, Got %s", s.String())
+	}
+}
@@ -0,0 +1,99 @@
+package bitmap
+
+import (
+	"errors"
+	"math"
+)
+
+// linearMaxLoad is the largest load factor, estimated items per bit,
+// for which LinearCounter estimates are considered reliable
+const linearMaxLoad = 5
+
+// ErrUnreliable is returned with an estimate when too many bits are set
+// for the estimate to be accurate
+var ErrUnreliable = errors.New("bitmap: load factor too high, estimate is unreliable")
+
+// LinearCounter estimate the number of distinct items by hashing them into a NBitmap
+type LinearCounter struct {
+	m    int // number of bits
+	bits *NBitmap
+}
+
+// NewLinearCounter return a new LinearCounter of m bits
+func NewLinearCounter(m int) *LinearCounter {
+	if m <= 0 {
+		return nil
+	}
+	return &LinearCounter{
+		m:    m,
+		bits: New(),
+	}
+}
+
+// Size return number of bits in counter
+func (l *LinearCounter) Size() int {
+	return l.m
+}
+
+// Add add data to the counter
+func (l *LinearCounter) Add(data []byte) {
+	l.bits.Add(int(hashBytes(data) % uint64(l.m)))
+}
+
+// AddString add s to the counter
+func (l *LinearCounter) AddString(s string) {
+	l.Add([]byte(s))
+}
+
+// AddInt add x to the counter
+func (l *LinearCounter) AddInt(x int) {
+	l.bits.Add(int(hashInt(uint64(x)) % uint64(l.m)))
+}
+
+// Load return the estimated load factor, distinct items per bit
+func (l *LinearCounter) Load() float64 {
+	zeros := l.m - l.bits.Len()
+	if zeros == 0 {
+		return math.Inf(1)
+	}
+	return -math.Log(float64(zeros) / float64(l.m))
+}
+
+// Estimate return the estimated number of distinct items
+// ErrUnreliable is returned with the estimate if the load factor is too high,
+// a larger counter should be used then
+func (l *LinearCounter) Estimate() (uint64, error) {
+	load := l.Load()
+	if math.IsInf(load, 1) {
+		// every bit is set, m ln m is the expected items to fill all bits
+		return uint64(float64(l.m)*math.Log(float64(l.m)) + 0.5), ErrUnreliable
+	}
+	estimate := uint64(float64(l.m)*load + 0.5)
+	if load > linearMaxLoad {
+		return estimate, ErrUnreliable
+	}
+	return estimate, nil
+}
+
+// Union l = l | c
+// items in l or c
+// l must have the same size of c
+func (l *LinearCounter) Union(c *LinearCounter) {
+	if l.m != c.m {
+		return
+	}
+	l.bits.Union(c.bits)
+}
+
+// Clear make the counter empty
+func (l *LinearCounter) Clear() {
+	*l = *NewLinearCounter(l.m)
+}
+
+// Copy return a copy counter
+func (l *LinearCounter) Copy() *LinearCounter {
+	return &LinearCounter{
+		m:    l.m,
+		bits: l.bits.Copy(),
+	}
+}
@@ -0,0 +1,59 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestLinearEstimate(t *testing.T) {
+	l := bitmap.NewLinearCounter(10000)
+	for i := 0; i < 5000; i++ {
+		l.AddString(strconv.Itoa(i))
+		l.AddString(strconv.Itoa(i))
+	}
+	n, err := l.Estimate()
+	if err != nil || math.Abs(float64(n)-5000)/5000 > 0.03 {
+		t.Errorf("TestLinearEstimate failed. Expected about 5000, Got %d %v", n, err)
+	}
+	if l.Load() < 0.45 || l.Load() > 0.55 {
+		t.Errorf("TestLinearEstimate failed. Expected load about 0.5, Got %f", l.Load())
+	}
+	l.Clear()
+	if n, err := l.Estimate(); n != 0 || err != nil {
+		t.Errorf("TestLinearEstimate Clear failed. Expected 0, Got %d %v", n, err)
+	}
+}
+
+func TestLinearUnreliable(t *testing.T) {
+	l := bitmap.NewLinearCounter(100)
+	for i := 0; i < 2000; i++ {
+		l.AddInt(i)
+	}
+	if _, err := l.Estimate(); err != bitmap.ErrUnreliable {
+		t.Errorf("TestLinearUnreliable failed. Expected ErrUnreliable, Got %v", err)
+	}
+}
+
+func TestLinearUnion(t *testing.T) {
+	a := bitmap.NewLinearCounter(20000)
+	b := bitmap.NewLinearCounter(20000)
+	for i := 0; i < 4000; i++ {
+		a.AddInt(i)
+	}
+	for i := 2000; i < 6000; i++ {
+		b.AddInt(i)
+	}
+	u := a.Copy()
+	u.Union(b)
+	n, err := u.Estimate()
+	if err != nil || math.Abs(float64(n)-6000)/6000 > 0.03 {
+		t.Errorf("TestLinearUnion failed. Expected about 6000, Got %d %v", n, err)
+	}
+	c := bitmap.NewLinearCounter(100)
+	c.Union(a)
+	if n, _ := c.Estimate(); n != 0 {
+		t.Errorf("TestLinearUnion of different sizes failed. Expected 0, Got %d", n)
+	}
+}
@@ -0,0 +1,259 @@
+package bitmap
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrBudget is returned by TryAdd, Grow, Union and SymExcept if the memory budget of bitmap is exceeded
+var ErrBudget = errors.New("bitmap: memory budget exceeded")
+
+const (
+	wordBytes = bitSize / 8
+	ptrBytes  = strconv.IntSize / 8
+	pageBytes = pageWords*wordBytes + strconv.IntSize/8 // words and count of a page
+	farBytes  = 3 * ptrBytes                            // keys and pointer of a far page
+)
+
+// NewWithCapacity return a new bitmap with the page table for elements in [0, maxValue]
+// pages are still allocated when they get the first element
+func NewWithCapacity(maxValue int) *NBitmap {
+	n := New()
+	n.Grow(maxValue + 1)
+	return n
+}
+
+// Grow make the page table large enough for elements less than x
+func (n *NBitmap) Grow(x int) error {
+	size := (x + pageBits - 1) / pageBits
+	if size <= cap(n.pages) {
+		return nil
+	}
+	if n.budget > 0 && n.MemoryUsage()+(size-cap(n.pages))*ptrBytes > n.budget {
+		return ErrBudget
+	}
+	pages := make([]*page, len(n.pages), size)
+	copy(pages, n.pages)
+	n.pages = pages
+	return nil
+}
+
+// Shrink free the spare page and the unused page table
+func (n *NBitmap) Shrink() {
+	if n.spare != nil {
+		n.spare = nil
+		n.allocated--
+	}
+	n.trim()
+	if cap(n.pages) > len(n.pages) {
+		n.pages = append([]*page(nil), n.pages...)
+	}
+}
+
+// MemoryUsage return the estimated bytes used by bitmap
+func (n *NBitmap) MemoryUsage() int {
+	return cap(n.pages)*ptrBytes + n.allocated*pageBytes + len(n.far)*farBytes
+}
+
+// SetBudget limit the memory of bitmap to bytes, 0 for no limit
+// TryAdd, Union and SymExcept return ErrBudget instead of growing past the limit,
+// Add ignore the element, memory already used is not freed
+func (n *NBitmap) SetBudget(bytes int) {
+	if bytes < 0 {
+		bytes = 0
+	}
+	n.budget = bytes
+}
+
+// addPage return the p-th page to add elements in, it's allocated if it's not
+// and the memory budget allows it, or ErrBudget is returned
+func (n *NBitmap) addPage(p int) (*page, error) {
+	if pg := n.lookup(p); pg != nil {
+		return pg, nil
+	}
+	if n.budget > 0 && n.MemoryUsage()+n.pageCost(p) > n.budget {
+		return nil, ErrBudget
+	}
+	return n.page(p), nil
+}
+
+// pageCost return bytes needed to allocate the p-th page
+func (n *NBitmap) pageCost(p int) int {
+	cost := 0
+	if n.spare == nil {
+		cost += pageBytes
+	}
+	switch {
+	case p < len(n.pages):
+	case n.isFar(p):
+		cost += farBytes
+	case p >= cap(n.pages):
+		cost += (n.tableCap(p+1) - cap(n.pages)) * ptrBytes
+	}
+	return cost
+}
+
+// NewRWithCapacity return a new bitmap count in [start, end) with words for elements in [start, maxValue]
+func NewRWithCapacity(start int, end int, maxValue int) *RBitmap {
+	r := NewR(start, end)
+	if r != nil {
+		r.Grow(maxValue + 1)
+	}
+	return r
+}
+
+// Grow make words large enough for elements less than x
+func (r *RBitmap) Grow(x int) error {
+	if x > r.end {
+		x = r.end
+	}
+	return reserveWords(&r.words, (x-r.start+bitSize-1)/bitSize, r.budget)
+}
+
+// Shrink free the words after the last element
+func (r *RBitmap) Shrink() {
+	r.words = shrinkWords(r.words)
+}
+
+// MemoryUsage return the bytes used by words of bitmap
+func (r *RBitmap) MemoryUsage() int {
+	return cap(r.words) * wordBytes
+}
+
+// SetBudget limit the memory of bitmap to bytes, 0 for no limit
+// TryAdd return ErrBudget instead of growing past the limit, Add ignore the element,
+// memory already used is not freed and set operations are not limited
+func (r *RBitmap) SetBudget(bytes int) {
+	if bytes < 0 {
+		bytes = 0
+	}
+	r.budget = bytes
+}
+
+// NewCWithCapacity return a new bitmap can count to n with words for elements in [0, maxValue]
+func NewCWithCapacity(n int, maxValue int) *CBitmap {
+	c := NewC(n)
+	if c != nil {
+		c.Grow(maxValue + 1)
+	}
+	return c
+}
+
+// Grow make words large enough for elements less than x
+func (c *CBitmap) Grow(x int) error {
+	return reserveWords(&c.words, (x+c.bitSize-1)/c.bitSize, c.budget)
+}
+
+// Shrink free the words after the last element
+func (c *CBitmap) Shrink() {
+	c.words = shrinkWords(c.words)
+}
+
+// MemoryUsage return the bytes used by words of bitmap
+func (c *CBitmap) MemoryUsage() int {
+	return cap(c.words) * wordBytes
+}
+
+// SetBudget limit the memory of bitmap to bytes, 0 for no limit
+// TryAdd return ErrBudget instead of growing past the limit, Add ignore the element,
+// memory already used is not freed
+func (c *CBitmap) SetBudget(bytes int) {
+	if bytes < 0 {
+		bytes = 0
+	}
+	c.budget = bytes
+}
+
+// NewRCWithCapacity return a new bitmap count in [start, end) and can count to n
+// with words for elements in [start, maxValue]
+func NewRCWithCapacity(start int, end int, n int, maxValue int) *RCBitmap {
+	rc := NewRC(start, end, n)
+	if rc != nil {
+		rc.Grow(maxValue + 1)
+	}
+	return rc
+}
+
+// Grow make words large enough for elements less than x
+func (rc *RCBitmap) Grow(x int) error {
+	if x > rc.end {
+		x = rc.end
+	}
+	return reserveWords(&rc.words, (x-rc.start+rc.bitSize-1)/rc.bitSize, rc.budget)
+}
+
+// Shrink free the words after the last element
+func (rc *RCBitmap) Shrink() {
+	rc.words = shrinkWords(rc.words)
+}
+
+// MemoryUsage return the bytes used by words of bitmap
+func (rc *RCBitmap) MemoryUsage() int {
+	return cap(rc.words) * wordBytes
+}
+
+// SetBudget limit the memory of bitmap to bytes, 0 for no limit
+// TryAdd return ErrBudget instead of growing past the limit, Add ignore the element,
+// memory already used is not freed
+func (rc *RCBitmap) SetBudget(bytes int) {
+	if bytes < 0 {
+		bytes = 0
+	}
+	rc.budget = bytes
+}
+
+// fitsWords return true if size words are in the budget
+func fitsWords(size int, budget int) bool {
+	return budget <= 0 || size*wordBytes <= budget
+}
+
+// growWords return words extended to size words, the words reused
+// in capacity are zeroed, the capacity never grows past the budget
+func growWords(words []bitInt, size int, budget int) []bitInt {
+	if size <= cap(words) {
+		old := len(words)
+		words = words[:size]
+		for i := old; i < size; i++ {
+			words[i] = 0
+		}
+		return words
+	}
+	capacity := 2 * cap(words)
+	if capacity < size {
+		capacity = size
+	}
+	if budget > 0 && capacity*wordBytes > budget {
+		capacity = budget / wordBytes
+	}
+	new := make([]bitInt, size, capacity)
+	copy(new, words)
+	return new
+}
+
+// reserveWords make the capacity of words at least size
+func reserveWords(words *[]bitInt, size int, budget int) error {
+	if size <= cap(*words) {
+		return nil
+	}
+	if !fitsWords(size, budget) {
+		return ErrBudget
+	}
+	new := make([]bitInt, len(*words), size)
+	copy(new, *words)
+	*words = new
+	return nil
+}
+
+// shrinkWords return words without the zero words at the end
+func shrinkWords(words []bitInt) []bitInt {
+	last := len(words)
+	for last > 0 && words[last-1] == 0 {
+		last--
+	}
+	if last == cap(words) {
+		return words
+	}
+	new := make([]bitInt, last)
+	copy(new, words)
+	return new
+}
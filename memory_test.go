@@ -0,0 +1,148 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestCapacity(t *testing.T) {
+	b := bitmap.NewWithCapacity(1 << 20)
+	before := b.MemoryUsage()
+	for i := 0; i < 1<<20; i += 1000 {
+		b.Add(i)
+	}
+	if b.Len() != 1049 || !b.Has(1048000) {
+		t.Errorf("TestCapacity failed. Expected 1049, Got %d", b.Len())
+	}
+	b.Add(1 << 20)
+	if b.MemoryUsage() <= before {
+		t.Errorf("TestCapacity failed. Expected pages allocated, Got %d bytes", b.MemoryUsage())
+	}
+	b.Clear()
+	if b.Len() != 0 || b.Has(0) || b.String() != "{}" {
+		t.Errorf("TestCapacity Clear failed. Expected {}, Got %s", b.String())
+	}
+	b.Add(5)
+	b.Shrink()
+	if b.MemoryUsage() >= before || b.String() != "{5}" {
+		t.Errorf("TestCapacity Shrink failed. Expected less than %d bytes, Got %d", before, b.MemoryUsage())
+	}
+}
+
+func TestCapacityOthers(t *testing.T) {
+	r := bitmap.NewRWithCapacity(-100, 100000, 9999)
+	c := bitmap.NewCWithCapacity(3, 9999)
+	rc := bitmap.NewRCWithCapacity(-100, 100000, 3, 9999)
+	if r.MemoryUsage() < 10000/8 || c.MemoryUsage() < 10000*2/8 || rc.MemoryUsage() < 10100*2/8 {
+		t.Errorf("TestCapacityOthers failed. Got %d %d %d bytes", r.MemoryUsage(), c.MemoryUsage(), rc.MemoryUsage())
+	}
+	before := []int{r.MemoryUsage(), c.MemoryUsage(), rc.MemoryUsage()}
+	r.Add(9999)
+	c.Add(9999)
+	rc.Add(9999)
+	if r.MemoryUsage() != before[0] || c.MemoryUsage() != before[1] || rc.MemoryUsage() != before[2] || !r.Has(9999) || c.Count(9999) != 1 || rc.Count(9999) != 1 {
+		t.Errorf("TestCapacityOthers failed. Expected no allocation, Got %d %d %d bytes", r.MemoryUsage(), c.MemoryUsage(), rc.MemoryUsage())
+	}
+	if bitmap.NewRWithCapacity(1, 1, 10) != nil || bitmap.NewCWithCapacity(0, 10) != nil || bitmap.NewRCWithCapacity(0, 10, 0, 10) != nil {
+		t.Errorf("TestCapacityOthers failed. Expected nil for invalid arguments")
+	}
+}
+
+func TestShrink(t *testing.T) {
+	r := bitmap.NewR(-100, 100000)
+	c := bitmap.NewC(3)
+	rc := bitmap.NewRC(-100, 100000, 3)
+	for i := -100; i < 100000; i += 7 {
+		r.Add(i)
+		c.Add(i)
+		rc.Add(i)
+	}
+	for i := 10; i < 100000; i++ {
+		r.Remove(i)
+		c.RemoveAll(i)
+		rc.RemoveAll(i)
+	}
+	large := r.MemoryUsage()
+	r.Shrink()
+	c.Shrink()
+	rc.Shrink()
+	if r.MemoryUsage() >= large/100 || c.MemoryUsage() >= large/100 || rc.MemoryUsage() >= large/100 {
+		t.Errorf("TestShrink failed. Expected less than %d bytes, Got %d %d %d", large/100, r.MemoryUsage(), c.MemoryUsage(), rc.MemoryUsage())
+	}
+	if r.String() != "{-100 -93 -86 -79 -72 -65 -58 -51 -44 -37 -30 -23 -16 -9 -2 5}" || c.String() != "{5}" || rc.String() != r.String() {
+		t.Errorf("TestShrink failed. Got %s %s %s", r.String(), c.String(), rc.String())
+	}
+	r.Clear()
+	r.Add(200)
+	if r.String() != "{200}" || r.Len() != 1 {
+		t.Errorf("TestShrink Clear failed. Expected {200}, Got %s", r.String())
+	}
+}
+
+func TestBudgetInterface(t *testing.T) {
+	bitmaps := []bitmap.BudgetBitmap{bitmap.New(), bitmap.NewR(0, 1<<20), bitmap.NewC(3), bitmap.NewRC(0, 1<<20, 3)}
+	for i, b := range bitmaps {
+		b.SetBudget(1024)
+		b.Add(10)
+		if err := b.TryAdd(1 << 19); err != bitmap.ErrBudget || !b.Has(10) || b.Has(1<<19) || b.Len() != 1 {
+			t.Errorf("TestBudgetInterface %d failed. Expected %v, Got %v %d", i, bitmap.ErrBudget, err, b.Len())
+		}
+	}
+	var _ bitmap.Bitmap = bitmap.NewDC(3)
+}
+
+func TestBudget(t *testing.T) {
+	b := bitmap.New()
+	b.SetBudget(64 * 1024)
+	b.Add(1)
+	if err := b.TryAdd(1 << 30); err != nil {
+		t.Errorf("TestBudget failed. Expected nil, Got %v", err)
+	}
+	var err error
+	for i := 0; err == nil; i += 1 << 20 {
+		err = b.TryAdd(i)
+	}
+	if err != bitmap.ErrBudget || b.MemoryUsage() > 64*1024 {
+		t.Errorf("TestBudget failed. Expected %v under %d bytes, Got %v %d", bitmap.ErrBudget, 64*1024, err, b.MemoryUsage())
+	}
+	if err := b.TryAdd(2); err != nil || !b.Has(2) {
+		t.Errorf("TestBudget failed. Expected nil for allocated page, Got %v", err)
+	}
+	// set operations stop at the budget like Add
+	src := bitmap.New()
+	for i := 0; i < 1<<24; i += 1 << 12 {
+		src.Add(i)
+	}
+	u, x := bitmap.New(), bitmap.New()
+	u.SetBudget(16 * 1024)
+	x.SetBudget(16 * 1024)
+	if u.Union(src) != bitmap.ErrBudget || x.SymExcept(src) != bitmap.ErrBudget ||
+		u.MemoryUsage() > 16*1024 || x.MemoryUsage() > 16*1024 || u.Len() == 0 || u.String() != x.String() {
+		t.Errorf("TestBudget Union failed. Expected %v under %d bytes, Got %d %d", bitmap.ErrBudget, 16*1024, u.MemoryUsage(), x.MemoryUsage())
+	}
+	u.SetBudget(0)
+	if u.Union(src) != nil || u.String() != src.String() {
+		t.Errorf("TestBudget Union failed. Expected no limit")
+	}
+
+	r := bitmap.NewR(0, 1<<20)
+	c := bitmap.NewC(3)
+	rc := bitmap.NewRC(0, 1<<20, 3)
+	r.SetBudget(1024)
+	c.SetBudget(1024)
+	rc.SetBudget(1024)
+	if r.TryAdd(1000) != nil || c.TryAdd(1000) != nil || rc.TryAdd(1000) != nil {
+		t.Errorf("TestBudget failed. Expected nil in budget")
+	}
+	if r.TryAdd(1<<19) != bitmap.ErrBudget || c.TryAdd(1<<19) != bitmap.ErrBudget || rc.TryAdd(1<<19) != bitmap.ErrBudget {
+		t.Errorf("TestBudget failed. Expected %v past budget", bitmap.ErrBudget)
+	}
+	r.Add(1 << 19)
+	if r.Has(1<<19) || r.Grow(1<<19) != bitmap.ErrBudget || r.MemoryUsage() > 1024 || c.MemoryUsage() > 1024 || rc.MemoryUsage() > 1024 {
+		t.Errorf("TestBudget failed. Expected under 1024 bytes, Got %d %d %d", r.MemoryUsage(), c.MemoryUsage(), rc.MemoryUsage())
+	}
+	r.SetBudget(0)
+	if r.TryAdd(1<<19) != nil || !r.Has(1<<19) {
+		t.Errorf("TestBudget failed. Expected no limit")
+	}
+}
@@ -0,0 +1,172 @@
+package bitmap
+
+import (
+	"math"
+	"sort"
+)
+
+// Signature is a MinHash signature of a set
+// the fraction of equal values of two signatures estimates the Jaccard similarity of sets
+type Signature []uint64
+
+// MinHash return the signature of n using k hash functions
+func MinHash(n *NBitmap, k int) Signature {
+	if k <= 0 {
+		return nil
+	}
+	seeds := minHashSeeds(k)
+	sig := make(Signature, k)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	n.Range(func(x int) bool {
+		for i, seed := range seeds {
+			if h := mix64(uint64(x) ^ seed); h < sig[i] {
+				sig[i] = h
+			}
+		}
+		return true
+	})
+	return sig
+}
+
+// Similarity return the estimated Jaccard similarity of sets of s and t
+// s must have the same length of t, or 0 is returned
+func (s Signature) Similarity(t Signature) float64 {
+	if len(s) != len(t) || len(s) == 0 {
+		return 0
+	}
+	equal := 0
+	for i := range s {
+		if s[i] == t[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(s))
+}
+
+// minHashSeeds return the seeds of k hash functions
+func minHashSeeds(k int) []uint64 {
+	seeds := make([]uint64, k)
+	for i := range seeds {
+		seeds[i] = hashInt(uint64(i))
+	}
+	return seeds
+}
+
+// LSHIndex find bitmaps with similar MinHash signatures by banding
+// signatures are split into bands of rows, bitmaps sharing any band are candidates
+type LSHIndex struct {
+	bands      int
+	rows       int
+	signatures map[int]Signature
+	buckets    []map[uint64][]int
+}
+
+// NewLSH return a new index of signatures with bands * rows values
+func NewLSH(bands int, rows int) *LSHIndex {
+	if bands <= 0 || rows <= 0 {
+		return nil
+	}
+	l := LSHIndex{}
+	l.bands = bands
+	l.rows = rows
+	l.signatures = make(map[int]Signature)
+	l.buckets = make([]map[uint64][]int, bands)
+	for i := range l.buckets {
+		l.buckets[i] = make(map[uint64][]int)
+	}
+	return &l
+}
+
+// Len return numbers of signatures in index
+func (l *LSHIndex) Len() int {
+	return len(l.signatures)
+}
+
+// Threshold return the similarity at which a pair becomes a candidate with probability 1/2
+// about (1/bands)^(1/rows)
+func (l *LSHIndex) Threshold() float64 {
+	return math.Pow(1/float64(l.bands), 1/float64(l.rows))
+}
+
+// Add add bitmap n with id to the index
+func (l *LSHIndex) Add(id int, n *NBitmap) {
+	l.AddSignature(id, MinHash(n, l.bands*l.rows))
+}
+
+// AddSignature add signature sig with id to the index
+// sig must have bands * rows values
+func (l *LSHIndex) AddSignature(id int, sig Signature) {
+	if len(sig) != l.bands*l.rows {
+		return
+	}
+	l.Remove(id)
+	l.signatures[id] = sig
+	for i := range l.buckets {
+		band := l.bandHash(sig, i)
+		l.buckets[i][band] = append(l.buckets[i][band], id)
+	}
+}
+
+// Remove remove id in index
+func (l *LSHIndex) Remove(id int) {
+	sig, ok := l.signatures[id]
+	if !ok {
+		return
+	}
+	delete(l.signatures, id)
+	for i := range l.buckets {
+		band := l.bandHash(sig, i)
+		ids := l.buckets[i][band]
+		for j, other := range ids {
+			if other == id {
+				ids = append(ids[:j], ids[j+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(l.buckets[i], band)
+		} else {
+			l.buckets[i][band] = ids
+		}
+	}
+}
+
+// Query return ids of bitmaps whose estimated similarity to n is at least threshold
+// ids are in increasing order
+func (l *LSHIndex) Query(n *NBitmap, threshold float64) []int {
+	return l.QuerySignature(MinHash(n, l.bands*l.rows), threshold)
+}
+
+// QuerySignature return ids of signatures whose similarity to sig is at least threshold
+// ids are in increasing order
+func (l *LSHIndex) QuerySignature(sig Signature, threshold float64) []int {
+	if len(sig) != l.bands*l.rows {
+		return nil
+	}
+	seen := make(map[int]bool)
+	var ids []int
+	for i := range l.buckets {
+		for _, id := range l.buckets[i][l.bandHash(sig, i)] {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if sig.Similarity(l.signatures[id]) >= threshold {
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// bandHash return the hash of band i of sig
+func (l *LSHIndex) bandHash(sig Signature, i int) uint64 {
+	h := uint64(i)
+	for _, v := range sig[i*l.rows : (i+1)*l.rows] {
+		h = mix64(h ^ v)
+	}
+	return h
+}
@@ -0,0 +1,55 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"math"
+	"testing"
+)
+
+func rangeBitmap(start int, end int) *bitmap.NBitmap {
+	n := bitmap.New()
+	for i := start; i < end; i++ {
+		n.Add(i)
+	}
+	return n
+}
+
+func TestMinHash(t *testing.T) {
+	a := rangeBitmap(0, 1000)
+	b := rangeBitmap(500, 1500)
+	sa := bitmap.MinHash(a, 256)
+	sb := bitmap.MinHash(b, 256)
+	// Jaccard similarity is 500 / 1500
+	if s := sa.Similarity(sb); math.Abs(s-1.0/3) > 0.08 {
+		t.Errorf("TestMinHash failed. Expected about 0.33, Got %f", s)
+	}
+	if s := sa.Similarity(bitmap.MinHash(a.Copy(), 256)); s != 1 {
+		t.Errorf("TestMinHash failed. Expected 1, Got %f", s)
+	}
+	if s := sa.Similarity(bitmap.MinHash(a, 128)); s != 0 {
+		t.Errorf("TestMinHash failed. Expected 0 for different lengths, Got %f", s)
+	}
+}
+
+func TestLSH(t *testing.T) {
+	l := bitmap.NewLSH(20, 5)
+	l.Add(1, rangeBitmap(0, 1000))
+	l.Add(2, rangeBitmap(50, 1050))
+	l.Add(3, rangeBitmap(5000, 6000))
+	l.Add(4, rangeBitmap(900, 1900))
+	if l.Len() != 4 {
+		t.Errorf("TestLSH failed. Expected 4, Got %d", l.Len())
+	}
+	if th := l.Threshold(); th < 0.5 || th > 0.6 {
+		t.Errorf("TestLSH failed. Expected threshold about 0.55, Got %f", th)
+	}
+	ids := l.Query(rangeBitmap(10, 1010), 0.7)
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("TestLSH Query failed. Expected [1 2], Got %v", ids)
+	}
+	l.Remove(2)
+	ids = l.Query(rangeBitmap(10, 1010), 0.7)
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("TestLSH Remove failed. Expected [1], Got %v", ids)
+	}
+}
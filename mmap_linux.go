@@ -0,0 +1,275 @@
+package bitmap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// file layout of MmapBitmap, all little endian uint64s:
+// magic, number of words, numbers in bitmap and 1 if the file was closed, then the words
+// the words are flat, so the file is as large as the largest element, but it's
+// grown by Truncate, words never written are holes without disk blocks
+const (
+	mmapMagic     = 0x32764d4d50414d42 // "BMAPMMv2"
+	mmapHeader    = 4 * 8
+	mmapInitWords = 512
+)
+
+var errMmapHeader = errors.New("bitmap: invalid mmap bitmap header")
+
+// ErrMmapRange is returned by MmapBitmap.Add if the element is larger than maxValue,
+// and by OpenMmap if maxValue is negative or the file has words past it
+var ErrMmapRange = errors.New("bitmap: element out of mmap bitmap range")
+
+// ErrMmapLocked is returned by OpenMmap if the file is opened by another MmapBitmap
+var ErrMmapLocked = errors.New("bitmap: mmap bitmap file is locked")
+
+// MmapBitmap is a NBitmap whose words are stored in a memory mapped file,
+// changes are written to the file by the kernel, Sync flush them,
+// the bitmap can be opened again by OpenMmap after the process restarts
+// words are not paged, elements must not be larger than maxValue of OpenMmap
+type MmapBitmap struct {
+	file     *os.File
+	data     []byte   // the mapped file
+	header   []uint64 // magic, number of words, numbers in bitmap and closed flag
+	words    []bitInt
+	maxValue int // largest element, the file has at most maxValue/bitSize+1 words
+}
+
+// OpenMmap open the bitmap in file path for elements in [0, maxValue],
+// the file is created if it does not exist, and it grows up to maxValue/8 bytes
+// numbers in bitmap are counted again if the file was not closed by Close
+// the file is locked until Close, so only one MmapBitmap can change it,
+// ErrMmapLocked is returned if it's opened by another one, in any process
+func OpenMmap(path string, maxValue int) (*MmapBitmap, error) {
+	if !nativeLittle {
+		return nil, errors.New("bitmap: mmap bitmap needs a little endian platform")
+	}
+	if maxValue < 0 {
+		return nil, ErrMmapRange
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// the lock is released when the file is closed
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrMmapLocked
+		}
+		return nil, err
+	}
+	m, err := openMmap(file, maxValue)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// openMmap map file and validate its header, a new header is written to an empty file
+// the number of words is the size of file, a smaller number in header is left by
+// a grow not finished, it's repaired
+func openMmap(file *os.File, maxValue int) (*MmapBitmap, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size, fresh, maxWords := info.Size(), info.Size() == 0, maxValue/bitSize+1
+	if fresh {
+		words := mmapInitWords
+		if words > maxWords {
+			words = maxWords
+		}
+		size = mmapHeader + int64(words)*8
+		if err := file.Truncate(size); err != nil {
+			return nil, err
+		}
+	}
+	if size <= mmapHeader || (size-mmapHeader)%8 != 0 {
+		return nil, errMmapHeader
+	}
+	if (size-mmapHeader)/8 > int64(maxWords) {
+		return nil, ErrMmapRange
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	m := &MmapBitmap{file: file, maxValue: maxValue}
+	m.load(data)
+	if fresh {
+		m.header[0], m.header[1] = mmapMagic, uint64(len(m.words))
+	}
+	if m.header[0] != mmapMagic || m.header[1] > uint64(len(m.words)) {
+		syscall.Munmap(data)
+		return nil, errMmapHeader
+	}
+	m.header[1] = uint64(len(m.words))
+	// numbers in header may not match the words if the file was not closed
+	if m.header[3] != 1 || m.header[2] > uint64(len(m.words))*bitSize {
+		m.header[2] = uint64(popcount(m.words))
+	}
+	// the flag must be cleared in the file before words are changed
+	m.header[3] = 0
+	if err := m.Sync(); err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	return m, nil
+}
+
+// load use the mapped data as header and words
+func (m *MmapBitmap) load(data []byte) {
+	m.data = data
+	m.header = unsafe.Slice((*uint64)(unsafe.Pointer(&data[0])), mmapHeader/8)
+	m.words = unsafe.Slice((*bitInt)(unsafe.Pointer(&data[mmapHeader])), (len(data)-mmapHeader)/8)
+}
+
+// String return formated string of bitmap
+func (m *MmapBitmap) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	m.Range(func(x int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers in bitmap
+func (m *MmapBitmap) Len() int {
+	return int(m.header[2])
+}
+
+// Has return true if x is in the bitmap
+func (m *MmapBitmap) Has(x int) bool {
+	if x < 0 {
+		return false
+	}
+	word, bit := x/bitSize, bitInt(x%bitSize)
+	return word < len(m.words) && m.words[word]&(1<<bit) != 0
+}
+
+// Add add x to the bitmap
+// the file is grown and mapped again if x is out of the words,
+// return ErrMmapRange if x is larger than maxValue
+func (m *MmapBitmap) Add(x int) error {
+	if x < 0 {
+		return nil
+	}
+	if x > m.maxValue {
+		return ErrMmapRange
+	}
+	word, bit := x/bitSize, bitInt(x%bitSize)
+	if word >= len(m.words) {
+		if err := m.grow(word + 1); err != nil {
+			return err
+		}
+	}
+	num := bitInt(1 << bit)
+	if m.words[word]&num == 0 {
+		m.header[2]++
+		m.words[word] |= num
+	}
+	return nil
+}
+
+// Remove remove x in bitmap
+func (m *MmapBitmap) Remove(x int) {
+	if x < 0 {
+		return
+	}
+	word, bit := x/bitSize, bitInt(x%bitSize)
+	if word < len(m.words) {
+		num := bitInt(1 << bit)
+		if m.words[word]&num != 0 {
+			m.header[2]--
+			m.words[word] &^= num
+		}
+	}
+}
+
+// Range call f with every element in increasing order
+// stop if f return false
+func (m *MmapBitmap) Range(f func(x int) bool) {
+	for i, word := range m.words {
+		for word != 0 {
+			if !f(i*bitSize + bits.TrailingZeros64(uint64(word))) {
+				return
+			}
+			word &= word - 1
+		}
+	}
+}
+
+// Bitmap return a NBitmap copy of the bitmap
+// its MarshalBinary encode the bitmap for Frozen
+func (m *MmapBitmap) Bitmap() *NBitmap {
+	return newFromWords(m.words)
+}
+
+// grow grow the file to at least size words and map it again
+// the number of words in header is updated at last, it's repaired by
+// OpenMmap if the process stops before
+func (m *MmapBitmap) grow(size int) error {
+	words := 2 * len(m.words)
+	if words < size {
+		words = size
+	}
+	if max := m.maxValue/bitSize + 1; words > max {
+		words = max
+	}
+	if err := m.file.Truncate(int64(mmapHeader + words*8)); err != nil {
+		return err
+	}
+	data, err := syscall.Mmap(int(m.file.Fd()), 0, mmapHeader+words*8, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	if err := syscall.Munmap(m.data); err != nil {
+		syscall.Munmap(data)
+		return err
+	}
+	m.load(data)
+	m.header[1] = uint64(words)
+	return nil
+}
+
+// Sync flush changes of the bitmap to the file
+func (m *MmapBitmap) Sync() error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&m.data[0])), uintptr(len(m.data)), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Close flush changes, mark the file closed, unmap the file and close it
+// the bitmap can not be used after Close
+func (m *MmapBitmap) Close() error {
+	err := m.Sync()
+	if err == nil {
+		// the flag is only set after the words are in the file
+		m.header[3] = 1
+		err = m.Sync()
+	}
+	if uerr := syscall.Munmap(m.data); err == nil {
+		err = uerr
+	}
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	m.data, m.header, m.words = nil, nil, nil
+	return err
+}
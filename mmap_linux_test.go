@@ -0,0 +1,139 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMmap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bitmap")
+	m, err := bitmap.OpenMmap(path, 1<<30)
+	if err != nil {
+		t.Fatalf("TestMmap failed. Got %v", err)
+	}
+	for _, x := range []int{-1, 1, 5, 64, 100000, 1 << 24, 5} {
+		if err := m.Add(x); err != nil {
+			t.Fatalf("TestMmap Add failed. Got %v", err)
+		}
+	}
+	m.Remove(64)
+	m.Remove(65)
+	if m.String() != "{1 5 100000 16777216}" || m.Len() != 4 || !m.Has(1<<24) || m.Has(64) {
+		t.Errorf("TestMmap failed. Expected {1 5 100000 16777216}, Got %s", m.String())
+	}
+	if err := m.Sync(); err != nil {
+		t.Errorf("TestMmap Sync failed. Got %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("TestMmap Close failed. Got %v", err)
+	}
+
+	m, err = bitmap.OpenMmap(path, 1<<30)
+	if err != nil || m.String() != "{1 5 100000 16777216}" || m.Len() != 4 {
+		t.Fatalf("TestMmap reopen failed. Expected {1 5 100000 16777216}, Got %v %v", m, err)
+	}
+	m.Add(2)
+	m.Close()
+
+	data, _ := os.ReadFile(path)
+	data[0] ^= 1
+	os.WriteFile(path, data, 0644)
+	if _, err := bitmap.OpenMmap(path, 1<<30); err == nil {
+		t.Errorf("TestMmap failed. Expected error for invalid magic")
+	}
+	data[0] ^= 1
+	os.WriteFile(path, data[:len(data)-8], 0644)
+	if _, err := bitmap.OpenMmap(path, 1<<30); err == nil {
+		t.Errorf("TestMmap failed. Expected error for invalid size")
+	}
+	os.WriteFile(path, data, 0644)
+	m, err = bitmap.OpenMmap(path, 1<<30)
+	if err != nil || m.Len() != 5 || !m.Has(2) {
+		t.Fatalf("TestMmap failed. Expected 5 elements, Got %v", err)
+	}
+	m.Close()
+}
+
+func TestMmapRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bitmap")
+	m, _ := bitmap.OpenMmap(path, 1<<30)
+	m.Add(3)
+	m.Add(1 << 20)
+	if err := m.Add(1<<30 + 1); err != bitmap.ErrMmapRange {
+		t.Errorf("TestMmapRecover failed. Expected ErrMmapRange, Got %v", err)
+	}
+	if b := m.Bitmap(); b.String() != "{3 1048576}" || b.Len() != 2 {
+		t.Errorf("TestMmapRecover Bitmap failed. Expected {3 1048576}, Got %s", b.String())
+	}
+	m.Close()
+	m, err := bitmap.OpenMmap(path, 1<<30)
+	if err != nil || m.String() != "{3 1048576}" || m.Len() != 2 {
+		t.Fatalf("TestMmapRecover grow failed. Expected {3 1048576}, Got %v %v", m, err)
+	}
+	m.Close()
+
+	// the file is grown but the header is not updated, and the count is wrong
+	data, _ := os.ReadFile(path)
+	words := binary.LittleEndian.Uint64(data[8:])
+	binary.LittleEndian.PutUint64(data[16:], 100)
+	binary.LittleEndian.PutUint64(data[24:], 0)
+	data = append(data, make([]byte, 8*words)...)
+	os.WriteFile(path, data, 0644)
+	m, err = bitmap.OpenMmap(path, 1<<30)
+	if err != nil || m.String() != "{3 1048576}" || m.Len() != 2 {
+		t.Fatalf("TestMmapRecover torn grow failed. Expected {3 1048576}, Got %v %v", m, err)
+	}
+	m.Add(2*int(words)*64 - 1)
+	m.Close()
+	data, _ = os.ReadFile(path)
+	if binary.LittleEndian.Uint64(data[8:]) != 2*words || binary.LittleEndian.Uint64(data[16:]) != 3 || binary.LittleEndian.Uint64(data[24:]) != 1 {
+		t.Errorf("TestMmapRecover failed. Expected header repaired, Got %x", data[:32])
+	}
+}
+
+func TestMmapOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bitmap")
+	if _, err := bitmap.OpenMmap(path, -1); err != bitmap.ErrMmapRange {
+		t.Errorf("TestMmapOpen failed. Expected ErrMmapRange for negative maxValue, Got %v", err)
+	}
+	m, err := bitmap.OpenMmap(path, 1<<20)
+	if err != nil {
+		t.Fatalf("TestMmapOpen failed. Got %v", err)
+	}
+	// only one MmapBitmap can change the file
+	if _, err := bitmap.OpenMmap(path, 1<<20); err != bitmap.ErrMmapLocked {
+		t.Errorf("TestMmapOpen failed. Expected ErrMmapLocked, Got %v", err)
+	}
+	if err := m.Add(1 << 20); err != nil {
+		t.Errorf("TestMmapOpen Add failed. Got %v", err)
+	}
+	m.Close()
+	// the file has words past a smaller maxValue
+	if _, err := bitmap.OpenMmap(path, 1<<19); err != bitmap.ErrMmapRange {
+		t.Errorf("TestMmapOpen failed. Expected ErrMmapRange for smaller maxValue, Got %v", err)
+	}
+	m, err = bitmap.OpenMmap(path, 1<<20)
+	if err != nil || !m.Has(1<<20) {
+		t.Fatalf("TestMmapOpen failed. Expected lock released by Close, Got %v", err)
+	}
+	m.Close()
+	if math.MaxInt == math.MaxInt32 {
+		return
+	}
+	// a 4GB file, words never written are holes
+	shift := 35
+	large := 1 << shift
+	path = filepath.Join(t.TempDir(), "large")
+	m, err = bitmap.OpenMmap(path, large)
+	if err != nil {
+		t.Fatalf("TestMmapOpen large failed. Got %v", err)
+	}
+	defer m.Close()
+	if err := m.Add(large); err != nil || !m.Has(large) || m.Len() != 1 {
+		t.Errorf("TestMmapOpen large failed. Expected %d, Got %v", large, err)
+	}
+}
@@ -0,0 +1,146 @@
+package bitmap
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PackedArray is an array of unsigned integers
+// every slot use exactly width bits, packed the same way as DCBitmap
+type PackedArray struct {
+	len   int
+	width int
+	words []bitInt
+}
+
+// NewPacked return a new array of n zero slots, every slot use width bits
+func NewPacked(width int, n int) *PackedArray {
+	if width <= 0 || width > bitSize || n < 0 {
+		return nil
+	}
+	return &PackedArray{
+		len:   n,
+		width: width,
+		words: make([]bitInt, (n*width+bitSize-1)/bitSize),
+	}
+}
+
+// String return formated string of array
+func (p *PackedArray) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < p.len; i++ {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", getField(p.words, i*p.width, p.width))
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// Len return numbers of slots in array
+func (p *PackedArray) Len() int {
+	return p.len
+}
+
+// Width return bits used by every slot
+func (p *PackedArray) Width() int {
+	return p.width
+}
+
+// Get return value of slot i, 0 if i is out of range
+func (p *PackedArray) Get(i int) uint64 {
+	if i < 0 || i >= p.len {
+		return 0
+	}
+	return uint64(getField(p.words, i*p.width, p.width))
+}
+
+// Set set slot i to v, only the low width bits of v are kept
+// nothing happens if i is out of range
+func (p *PackedArray) Set(i int, v uint64) {
+	if i < 0 || i >= p.len {
+		return
+	}
+	setField(p.words, i*p.width, p.width, bitInt(v))
+}
+
+// Append add values to the end of array
+func (p *PackedArray) Append(values ...uint64) {
+	n := p.len + len(values)
+	if words := (n*p.width + bitSize - 1) / bitSize; words > len(p.words) {
+		p.words = append(p.words, make([]bitInt, words-len(p.words))...)
+	}
+	for _, v := range values {
+		setField(p.words, p.len*p.width, p.width, bitInt(v))
+		p.len++
+	}
+}
+
+// Fill set slots in [start, end) to v
+func (p *PackedArray) Fill(start int, end int, v uint64) {
+	if start < 0 {
+		start = 0
+	}
+	if end > p.len {
+		end = p.len
+	}
+	if start >= end {
+		return
+	}
+	for i := start; i < end; i++ {
+		setField(p.words, i*p.width, p.width, bitInt(v))
+	}
+}
+
+// Uint64s append all values to dst and return it
+func (p *PackedArray) Uint64s(dst []uint64) []uint64 {
+	p.decode(func(v bitInt) {
+		dst = append(dst, uint64(v))
+	})
+	return dst
+}
+
+// Uint32s append all values to dst and return it
+// values wider than 32 bits are truncated
+func (p *PackedArray) Uint32s(dst []uint32) []uint32 {
+	p.decode(func(v bitInt) {
+		dst = append(dst, uint32(v))
+	})
+	return dst
+}
+
+// decode call fn with every value in order
+// words are read sequentially instead of locating every slot
+func (p *PackedArray) decode(fn func(v bitInt)) {
+	mask := bitInt(1)<<bitInt(p.width) - 1
+	word, off := 0, 0
+	for i := 0; i < p.len; i++ {
+		v := p.words[word] >> bitInt(off)
+		off += p.width
+		if off >= bitSize {
+			off -= bitSize
+			word++
+			if off > 0 {
+				v |= p.words[word] << bitInt(p.width-off)
+			}
+		}
+		fn(v & mask)
+	}
+}
+
+// Clear make the array empty
+func (p *PackedArray) Clear() {
+	*p = *NewPacked(p.width, 0)
+}
+
+// Copy return a copy array
+func (p *PackedArray) Copy() *PackedArray {
+	new := PackedArray{}
+	new.len = p.len
+	new.width = p.width
+	new.words = make([]bitInt, len(p.words))
+	copy(new.words, p.words)
+	return &new
+}
@@ -0,0 +1,91 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestPackedGetSet(t *testing.T) {
+	p := bitmap.NewPacked(5, 100)
+	for i := 0; i < 100; i++ {
+		p.Set(i, uint64(i))
+	}
+	p.Set(-1, 1)
+	p.Set(100, 1)
+	for i := 0; i < 100; i++ {
+		if p.Get(i) != uint64(i%32) {
+			t.Fatalf("TestPackedGetSet failed. Expected %d, Got %d", i%32, p.Get(i))
+		}
+	}
+	if p.Len() != 100 || p.Get(100) != 0 || p.Get(-1) != 0 {
+		t.Errorf("TestPackedGetSet failed.")
+	}
+}
+
+func TestPackedAppend(t *testing.T) {
+	p := bitmap.NewPacked(3, 0)
+	p.Append(1, 2, 3)
+	p.Append(7, 8)
+	if p.Len() != 5 || p.String() != "[1 2 3 7 0]" {
+		t.Errorf("TestPackedAppend failed. Expected [1 2 3 7 0], Got %s", p.String())
+	}
+}
+
+func TestPackedFill(t *testing.T) {
+	p := bitmap.NewPacked(3, 6)
+	p.Fill(1, 4, 5)
+	p.Fill(5, 10, 2)
+	if p.String() != "[0 5 5 5 0 2]" {
+		t.Errorf("TestPackedFill failed. Expected [0 5 5 5 0 2], Got %s", p.String())
+	}
+}
+
+func TestPackedDecode(t *testing.T) {
+	for _, width := range []int{1, 3, 7, 13, 32} {
+		p := bitmap.NewPacked(width, 0)
+		for i := 0; i < 300; i++ {
+			p.Append(uint64(i * 7919))
+		}
+		u64 := p.Uint64s(nil)
+		u32 := p.Uint32s(nil)
+		if len(u64) != 300 || len(u32) != 300 {
+			t.Fatalf("TestPackedDecode failed. Expected 300 values, Got %d", len(u64))
+		}
+		for i := range u64 {
+			if u64[i] != p.Get(i) || uint64(u32[i]) != p.Get(i) {
+				t.Fatalf("TestPackedDecode width %d failed at %d. Expected %d, Got %d", width, i, p.Get(i), u64[i])
+			}
+		}
+	}
+}
+
+func TestPackedCopy(t *testing.T) {
+	p := bitmap.NewPacked(4, 0)
+	p.Append(1, 2, 3)
+	c := p.Copy()
+	p.Set(0, 9)
+	p.Clear()
+	if c.String() != "[1 2 3]" || p.Len() != 0 {
+		t.Errorf("TestPackedCopy failed. Expected [1 2 3], Got %s", c.String())
+	}
+}
+
+func BenchmarkPackedDecode(b *testing.B) {
+	p := bitmap.NewPacked(5, 100000)
+	dst := make([]uint32, 0, p.Len())
+	for i := 0; i < b.N; i++ {
+		dst = p.Uint32s(dst[:0])
+	}
+}
+
+func TestPackedWide(t *testing.T) {
+	// words are 64 bits on all platforms
+	p := bitmap.NewPacked(48, 3)
+	if p == nil || bitmap.NewPacked(64, 1) == nil || bitmap.NewDC(40) == nil {
+		t.Fatalf("TestPackedWide failed. Expected widths up to 64")
+	}
+	p.Set(1, 1<<47|5)
+	if p.Get(1) != 1<<47|5 || p.Get(0) != 0 || p.Get(2) != 0 {
+		t.Errorf("TestPackedWide failed. Expected %d, Got %d", uint64(1<<47|5), p.Get(1))
+	}
+}
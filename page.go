@@ -0,0 +1,250 @@
+package bitmap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	pageWords    = 64 // words in every page of NBitmap
+	pageBits     = pageWords * bitSize
+	pageTableMin = 64 // the page table always grows to this many pages
+)
+
+// page is a block of words of NBitmap
+type page struct {
+	count int // numbers in page
+	words [pageWords]bitInt
+}
+
+// lookup return the p-th page, nil if it's not allocated
+func (n *NBitmap) lookup(p int) *page {
+	if p < len(n.pages) {
+		return n.pages[p]
+	}
+	return n.far[p]
+}
+
+// isFar return true if the p-th page is kept out of the page table
+// the page table only grows to p if it's in the capacity or not too far
+// from the last page, so a stray large element costs one page
+func (n *NBitmap) isFar(p int) bool {
+	return p >= cap(n.pages) && p >= 2*len(n.pages)+pageTableMin
+}
+
+// page return the p-th page, allocate it if it's not allocated
+func (n *NBitmap) page(p int) *page {
+	if p >= len(n.pages) {
+		if pg := n.far[p]; pg != nil {
+			return pg
+		}
+		if n.isFar(p) {
+			pg := n.alloc()
+			n.addFar(p, pg)
+			return pg
+		}
+		n.grow(p + 1)
+	}
+	if n.pages[p] == nil {
+		n.pages[p] = n.alloc()
+	}
+	return n.pages[p]
+}
+
+// grow grow the page table to size pages, far pages in it are moved into it
+func (n *NBitmap) grow(size int) {
+	if size <= cap(n.pages) {
+		// pages after len are always nil
+		n.pages = n.pages[:size]
+	} else {
+		pages := make([]*page, size, n.tableCap(size))
+		copy(pages, n.pages)
+		n.pages = pages
+	}
+	for len(n.farKeys) > 0 && n.farKeys[0] < size {
+		p := n.farKeys[0]
+		n.pages[p] = n.far[p]
+		n.deleteFar(p)
+	}
+}
+
+// addFar add pg as the p-th page out of the page table
+func (n *NBitmap) addFar(p int, pg *page) {
+	if n.far == nil {
+		n.far = make(map[int]*page)
+	}
+	n.far[p] = pg
+	i := sort.SearchInts(n.farKeys, p)
+	n.farKeys = append(n.farKeys, 0)
+	copy(n.farKeys[i+1:], n.farKeys[i:])
+	n.farKeys[i] = p
+}
+
+// deleteFar delete the p-th page out of the page table
+func (n *NBitmap) deleteFar(p int) {
+	delete(n.far, p)
+	i := sort.SearchInts(n.farKeys, p)
+	n.farKeys = append(n.farKeys[:i], n.farKeys[i+1:]...)
+}
+
+// tableCap return the capacity of page table grown to size pages
+func (n *NBitmap) tableCap(size int) int {
+	if size < 2*cap(n.pages) {
+		return 2 * cap(n.pages)
+	}
+	return size
+}
+
+// alloc return an empty page
+func (n *NBitmap) alloc() *page {
+	if pg := n.spare; pg != nil {
+		n.spare = nil
+		return pg
+	}
+	n.allocated++
+	return new(page)
+}
+
+// release release the p-th page which has no elements
+// it's kept as spare so adding and removing around the same
+// element does not allocate again and again
+// the page table is not trimmed, set operations trim it
+func (n *NBitmap) release(p int) {
+	if n.spare != nil {
+		n.allocated--
+	}
+	if p < len(n.pages) {
+		n.spare, n.pages[p] = n.pages[p], nil
+		return
+	}
+	n.spare = n.far[p]
+	n.deleteFar(p)
+}
+
+// trim drop the released pages at the end of page table
+func (n *NBitmap) trim() {
+	last := len(n.pages)
+	for last > 0 && n.pages[last-1] == nil {
+		last--
+	}
+	n.pages = n.pages[:last]
+}
+
+// rangePages call f with every allocated page in increasing order
+// stop if f return false, f must not add or release pages
+func (n *NBitmap) rangePages(f func(p int, pg *page) bool) {
+	for p, pg := range n.pages {
+		if pg != nil && !f(p, pg) {
+			return
+		}
+	}
+	for _, p := range n.farKeys {
+		if !f(p, n.far[p]) {
+			return
+		}
+	}
+}
+
+// mergePage or cpg into the p-th page, or xor it if sym is true
+// the page is allocated in the memory budget and released if it becomes empty
+func (n *NBitmap) mergePage(p int, cpg *page, sym bool) error {
+	pg, err := n.addPage(p)
+	if err != nil {
+		return err
+	}
+	// words are counted in the same pass
+	count := 0
+	if sym {
+		for i, cword := range cpg.words {
+			pg.words[i] ^= cword
+			count += bits.OnesCount64(uint64(pg.words[i]))
+		}
+	} else {
+		for i, cword := range cpg.words {
+			pg.words[i] |= cword
+			count += bits.OnesCount64(uint64(pg.words[i]))
+		}
+	}
+	n.len += count - pg.count
+	pg.count = count
+	if pg.count == 0 {
+		n.release(p)
+	}
+	return nil
+}
+
+// recount count numbers in every page after words are changed,
+// and release the pages become empty
+func (n *NBitmap) recount() {
+	n.len = 0
+	for p, pg := range n.pages {
+		if pg == nil {
+			continue
+		}
+		pg.count = popcount(pg.words[:])
+		if pg.count == 0 {
+			n.allocated--
+			n.pages[p] = nil
+		}
+		n.len += pg.count
+	}
+	keys := n.farKeys[:0]
+	for _, p := range n.farKeys {
+		pg := n.far[p]
+		pg.count = popcount(pg.words[:])
+		if pg.count == 0 {
+			n.allocated--
+			delete(n.far, p)
+			continue
+		}
+		keys = append(keys, p)
+		n.len += pg.count
+	}
+	n.farKeys = keys
+	n.trim()
+}
+
+// wordLen return numbers of words covered by the page table, far pages excluded
+func (n *NBitmap) wordLen() int {
+	return len(n.pages) * pageWords
+}
+
+// setWord set the i-th word to w
+func (n *NBitmap) setWord(i int, w bitInt) {
+	p := i / pageWords
+	if w == 0 && n.lookup(p) == nil {
+		return
+	}
+	pg := n.page(p)
+	diff := bits.OnesCount64(uint64(w)) - bits.OnesCount64(uint64(pg.words[i%pageWords]))
+	pg.words[i%pageWords] = w
+	pg.count += diff
+	n.len += diff
+	if pg.count == 0 {
+		n.release(p)
+	}
+}
+
+// words return the first count words as a slice
+func (n *NBitmap) words(count int) []bitInt {
+	words := make([]bitInt, count)
+	n.rangePages(func(p int, pg *page) bool {
+		if p*pageWords >= count {
+			return false
+		}
+		copy(words[p*pageWords:], pg.words[:])
+		return true
+	})
+	return words
+}
+
+// newFromWords return a new bitmap of words
+func newFromWords(words []bitInt) *NBitmap {
+	n := New()
+	for i, word := range words {
+		if word != 0 {
+			n.setWord(i, word)
+		}
+	}
+	return n
+}
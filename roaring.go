@@ -0,0 +1,753 @@
+package bitmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+// containers and the 32 bits roaring bitmap under Roaring64,
+// the layout and serialization follow the roaring portable format
+// https://github.com/RoaringBitmap/RoaringFormatSpec
+const (
+	arrayMax          = 4096 // largest cardinality of array containers
+	containerBits     = 1 << 16
+	containerWords    = containerBits / bitSize
+	serialCookieNoRun = 12346 // cookie of bitmaps without run containers
+	serialCookie      = 12347 // cookie of bitmaps with run containers
+	noOffsetThreshold = 4     // bitmaps with run containers and fewer containers have no offsets
+)
+
+var errRoaring = errors.New("bitmap: invalid roaring data")
+
+// run16 is the run [start, last] of a run container
+type run16 struct {
+	start, last uint16
+}
+
+// container hold the low 16 bits of numbers sharing the high bits
+// only one of array, words and runs is not nil: array for at most
+// arrayMax numbers, words for more, runs if it's smaller than both
+type container struct {
+	card  int
+	array []uint16 // increasing numbers
+	words []bitInt // containerWords words
+	runs  []run16  // increasing disjoint runs
+}
+
+// newContainer return the smallest container of numbers in words, nil if words is empty
+// words are owned by the container if it's a bitmap
+func newContainer(words []bitInt) *container {
+	c := &container{}
+	c.load(words)
+	if c.card == 0 {
+		return nil
+	}
+	if n := countRuns(words); 2+4*n < c.size() {
+		c.runs, c.array, c.words = wordRuns(words, n), nil, nil
+	}
+	return c
+}
+
+// load set c to numbers in words as an array or a bitmap
+func (c *container) load(words []bitInt) {
+	c.card, c.runs = popcount(words), nil
+	if c.card > arrayMax {
+		c.array, c.words = nil, words
+		return
+	}
+	c.array, c.words = make([]uint16, 0, c.card), nil
+	for i, word := range words {
+		for word != 0 {
+			c.array = append(c.array, uint16(i*bitSize+bits.TrailingZeros64(uint64(word))))
+			word &= word - 1
+		}
+	}
+}
+
+// size return bytes of c in the portable format
+func (c *container) size() int {
+	switch {
+	case c.runs != nil:
+		return 2 + 4*len(c.runs)
+	case c.words != nil:
+		return containerWords * 8
+	default:
+		return 2 * c.card
+	}
+}
+
+// toWords return a new bitmap of numbers in c
+func (c *container) toWords() []bitInt {
+	words := make([]bitInt, containerWords)
+	switch {
+	case c.array != nil:
+		for _, v := range c.array {
+			words[v/bitSize] |= 1 << bitInt(v%bitSize)
+		}
+	case c.words != nil:
+		copy(words, c.words)
+	default:
+		for _, r := range c.runs {
+			fillBits(words, int(r.start), int(r.last)+1, true)
+		}
+	}
+	return words
+}
+
+// copy return a copy container
+func (c *container) copy() *container {
+	new := container{}
+	new.card = c.card
+	if c.array != nil {
+		new.array = append([]uint16{}, c.array...)
+	}
+	if c.words != nil {
+		new.words = append([]bitInt{}, c.words...)
+	}
+	if c.runs != nil {
+		new.runs = append([]run16{}, c.runs...)
+	}
+	return &new
+}
+
+// has return true if v is in c
+func (c *container) has(v uint16) bool {
+	switch {
+	case c.array != nil:
+		i := sort.Search(len(c.array), func(i int) bool {
+			return c.array[i] >= v
+		})
+		return i < len(c.array) && c.array[i] == v
+	case c.words != nil:
+		return c.words[v/bitSize]&(1<<bitInt(v%bitSize)) != 0
+	default:
+		i := sort.Search(len(c.runs), func(i int) bool {
+			return c.runs[i].last >= v
+		})
+		return i < len(c.runs) && c.runs[i].start <= v
+	}
+}
+
+// add add v to c, return false if v is in c
+// run containers become arrays or bitmaps, RunOptimize compress them again
+func (c *container) add(v uint16) bool {
+	if c.has(v) {
+		return false
+	}
+	if c.runs != nil {
+		c.load(c.toWords())
+	}
+	c.card++
+	if c.words != nil {
+		c.words[v/bitSize] |= 1 << bitInt(v%bitSize)
+		return true
+	}
+	if c.card > arrayMax {
+		words := c.toWords()
+		words[v/bitSize] |= 1 << bitInt(v%bitSize)
+		c.array, c.words = nil, words
+		return true
+	}
+	i := sort.Search(len(c.array), func(i int) bool {
+		return c.array[i] > v
+	})
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = v
+	return true
+}
+
+// remove remove v in c, return false if v is not in c
+func (c *container) remove(v uint16) bool {
+	if !c.has(v) {
+		return false
+	}
+	if c.runs != nil {
+		c.load(c.toWords())
+	}
+	c.card--
+	if c.words != nil {
+		c.words[v/bitSize] &^= 1 << bitInt(v%bitSize)
+		if c.card <= arrayMax {
+			c.load(c.words)
+		}
+		return true
+	}
+	i := sort.Search(len(c.array), func(i int) bool {
+		return c.array[i] >= v
+	})
+	c.array = append(c.array[:i], c.array[i+1:]...)
+	return true
+}
+
+// fillRange return c with numbers in [lo, hi) added or removed, nil if it's empty
+// c may be nil for an empty container
+func (c *container) fillRange(lo int, hi int, set bool) *container {
+	if lo == 0 && hi == containerBits {
+		if !set {
+			return nil
+		}
+		return &container{card: containerBits, runs: []run16{{0, containerBits - 1}}}
+	}
+	var words []bitInt
+	if c == nil {
+		words = make([]bitInt, containerWords)
+	} else {
+		words = c.toWords()
+	}
+	fillBits(words, lo, hi, set)
+	return newContainer(words)
+}
+
+// optimize return the smallest container of numbers in c
+func (c *container) optimize() *container {
+	return newContainer(c.toWords())
+}
+
+// rangeFrom call f with every number in c plus base in increasing order
+// return false if f return false
+func (c *container) rangeFrom(base uint64, f func(x uint64) bool) bool {
+	switch {
+	case c.array != nil:
+		for _, v := range c.array {
+			if !f(base + uint64(v)) {
+				return false
+			}
+		}
+	case c.words != nil:
+		for i, word := range c.words {
+			for word != 0 {
+				if !f(base + uint64(i*bitSize+bits.TrailingZeros64(uint64(word)))) {
+					return false
+				}
+				word &= word - 1
+			}
+		}
+	default:
+		for _, r := range c.runs {
+			for v := int(r.start); v <= int(r.last); v++ {
+				if !f(base + uint64(v)) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// rank return numbers in c less than or equal to v
+func (c *container) rank(v uint16) int {
+	switch {
+	case c.array != nil:
+		return sort.Search(len(c.array), func(i int) bool {
+			return c.array[i] > v
+		})
+	case c.words != nil:
+		w := int(v / bitSize)
+		return popcount(c.words[:w]) + bits.OnesCount64(uint64(c.words[w]&(2<<bitInt(v%bitSize)-1)))
+	default:
+		r := 0
+		for _, run := range c.runs {
+			if run.start > v {
+				break
+			}
+			if run.last >= v {
+				return r + int(v-run.start) + 1
+			}
+			r += int(run.last-run.start) + 1
+		}
+		return r
+	}
+}
+
+// selectAt return the i-th smallest number in c, i must be in [0, card)
+func (c *container) selectAt(i int) uint16 {
+	switch {
+	case c.array != nil:
+		return c.array[i]
+	case c.words != nil:
+		return uint16(selectBit(c.words, 0, i, false))
+	default:
+		for _, run := range c.runs {
+			n := int(run.last-run.start) + 1
+			if i < n {
+				return run.start + uint16(i)
+			}
+			i -= n
+		}
+		return 0
+	}
+}
+
+// combine return op of a and b word by word, nil if it's empty
+// two arrays are merged without bitmaps, so sparse numbers stay cheap
+func combine(a *container, b *container, op func(x, y bitInt) bitInt) *container {
+	if a.array != nil && b.array != nil {
+		return combineArrays(a.array, b.array, op)
+	}
+	words, other := a.toWords(), b.toWords()
+	for i := range words {
+		words[i] = op(words[i], other[i])
+	}
+	return newContainer(words)
+}
+
+// combineArrays return op of the numbers in arrays a and b, nil if it's empty
+// it's the same container as combine of their bitmaps
+func combineArrays(a []uint16, b []uint16, op func(x, y bitInt) bitInt) *container {
+	array := make([]uint16, 0, len(a)+len(b))
+	runs := 0
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		var v uint16
+		var x, y bitInt
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			v, x = a[i], 1
+			i++
+		case i >= len(a) || b[j] < a[i]:
+			v, y = b[j], 1
+			j++
+		default:
+			v, x, y = a[i], 1, 1
+			i++
+			j++
+		}
+		if op(x, y)&1 == 0 {
+			continue
+		}
+		if len(array) == 0 || array[len(array)-1]+1 != v {
+			runs++
+		}
+		array = append(array, v)
+	}
+	c := &container{card: len(array), array: array}
+	if c.card == 0 {
+		return nil
+	}
+	if c.card > arrayMax || 2+4*runs < c.size() {
+		return newContainer(c.toWords())
+	}
+	return c
+}
+
+// appendTo append c in the portable format to b
+func (c *container) appendTo(b []byte) []byte {
+	switch {
+	case c.runs != nil:
+		b = appendUint16(b, uint16(len(c.runs)))
+		for _, r := range c.runs {
+			b = appendUint16(b, r.start)
+			b = appendUint16(b, r.last-r.start)
+		}
+		return b
+	case c.words != nil:
+		return appendWords(b, c.words)
+	default:
+		for _, v := range c.array {
+			b = appendUint16(b, v)
+		}
+		return b
+	}
+}
+
+// readContainer read a container of card numbers in the portable format
+// return the container and bytes read
+func readContainer(data []byte, card int, isRun bool) (*container, int, error) {
+	c := &container{card: card}
+	switch {
+	case isRun:
+		if len(data) < 2 {
+			return nil, 0, errShortBuffer
+		}
+		n := int(binary.LittleEndian.Uint16(data))
+		if len(data) < 2+4*n {
+			return nil, 0, errShortBuffer
+		}
+		c.runs = make([]run16, n)
+		count, next := 0, 0 // next is the smallest start of the next run
+		for i := range c.runs {
+			start := int(binary.LittleEndian.Uint16(data[2+4*i:]))
+			length := int(binary.LittleEndian.Uint16(data[4+4*i:]))
+			if start < next || start+length >= containerBits {
+				return nil, 0, errRoaring
+			}
+			c.runs[i] = run16{uint16(start), uint16(start + length)}
+			count += length + 1
+			next = start + length + 1
+		}
+		if count != card {
+			return nil, 0, errRoaring
+		}
+		return c, 2 + 4*n, nil
+	case card <= arrayMax:
+		if len(data) < 2*card {
+			return nil, 0, errShortBuffer
+		}
+		c.array = make([]uint16, card)
+		for i := range c.array {
+			c.array[i] = binary.LittleEndian.Uint16(data[2*i:])
+			if i > 0 && c.array[i] <= c.array[i-1] {
+				return nil, 0, errRoaring
+			}
+		}
+		return c, 2 * card, nil
+	default:
+		words, err := readWords(data, containerWords)
+		if err != nil {
+			return nil, 0, err
+		}
+		if popcount(words) != card {
+			return nil, 0, errRoaring
+		}
+		c.words = words
+		return c, containerWords * 8, nil
+	}
+}
+
+// fillBits set or clear bits in [lo, hi) of words
+func fillBits(words []bitInt, lo int, hi int, set bool) {
+	for lo < hi {
+		n := bitSize - lo%bitSize
+		if hi-lo < n {
+			n = hi - lo
+		}
+		mask := ^bitInt(0) >> bitInt(bitSize-n) << bitInt(lo%bitSize)
+		if set {
+			words[lo/bitSize] |= mask
+		} else {
+			words[lo/bitSize] &^= mask
+		}
+		lo += n
+	}
+}
+
+// countRuns return numbers of runs of ones in words
+func countRuns(words []bitInt) int {
+	n := 0
+	var prev bitInt
+	for _, word := range words {
+		n += bits.OnesCount64(uint64(word &^ (word<<1 | prev>>(bitSize-1))))
+		prev = word
+	}
+	return n
+}
+
+// wordRuns return the n runs of ones in words
+func wordRuns(words []bitInt, n int) []run16 {
+	runs := make([]run16, 0, n)
+	start := -1
+	for i, word := range words {
+		if (word == 0 && start < 0) || (word == ^bitInt(0) && start >= 0) {
+			continue
+		}
+		for j := 0; j < bitSize; j++ {
+			set := word&(1<<bitInt(j)) != 0
+			if set && start < 0 {
+				start = i*bitSize + j
+			} else if !set && start >= 0 {
+				runs = append(runs, run16{uint16(start), uint16(i*bitSize + j - 1)})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		runs = append(runs, run16{uint16(start), containerBits - 1})
+	}
+	return runs
+}
+
+// roaring32 is a roaring bitmap of uint32 numbers,
+// the high 16 bits are the key of the container of the low 16 bits
+type roaring32 struct {
+	keys       []uint16 // increasing
+	containers []*container
+}
+
+// find return the index of key in keys, false if key is not found
+// the index is where key should be inserted if it's not found
+func (r *roaring32) find(key uint16) (int, bool) {
+	i := sort.Search(len(r.keys), func(i int) bool {
+		return r.keys[i] >= key
+	})
+	return i, i < len(r.keys) && r.keys[i] == key
+}
+
+// card return numbers in r
+func (r *roaring32) card() uint64 {
+	n := uint64(0)
+	for _, c := range r.containers {
+		n += uint64(c.card)
+	}
+	return n
+}
+
+// has return true if x is in r
+func (r *roaring32) has(x uint32) bool {
+	i, ok := r.find(uint16(x >> 16))
+	return ok && r.containers[i].has(uint16(x))
+}
+
+// add add x to r, return false if x is in r
+func (r *roaring32) add(x uint32) bool {
+	i, ok := r.find(uint16(x >> 16))
+	if !ok {
+		r.keys = append(r.keys, 0)
+		copy(r.keys[i+1:], r.keys[i:])
+		r.keys[i] = uint16(x >> 16)
+		r.containers = append(r.containers, nil)
+		copy(r.containers[i+1:], r.containers[i:])
+		r.containers[i] = &container{array: []uint16{}}
+	}
+	return r.containers[i].add(uint16(x))
+}
+
+// remove remove x in r, return false if x is not in r
+func (r *roaring32) remove(x uint32) bool {
+	i, ok := r.find(uint16(x >> 16))
+	if !ok || !r.containers[i].remove(uint16(x)) {
+		return false
+	}
+	if r.containers[i].card == 0 {
+		r.keys = append(r.keys[:i], r.keys[i+1:]...)
+		copy(r.containers[i:], r.containers[i+1:])
+		r.containers[len(r.containers)-1] = nil
+		r.containers = r.containers[:len(r.containers)-1]
+	}
+	return true
+}
+
+// fillRange add or remove numbers in [lo, hi), hi is at most 1<<32
+func (r *roaring32) fillRange(lo uint64, hi uint64, set bool) {
+	if lo >= hi {
+		return
+	}
+	first, last := int(lo>>16), int((hi-1)>>16)
+	i, _ := r.find(uint16(first))
+	keys := append([]uint16(nil), r.keys[:i]...)
+	containers := append([]*container(nil), r.containers[:i]...)
+	for k := first; k <= last; k++ {
+		var c *container
+		if i < len(r.keys) && int(r.keys[i]) == k {
+			c = r.containers[i]
+			i++
+		} else if !set {
+			// nothing to remove before the next container
+			if i >= len(r.keys) || int(r.keys[i]) > last {
+				break
+			}
+			k = int(r.keys[i]) - 1
+			continue
+		}
+		base := uint64(k) << 16
+		start, end := 0, containerBits
+		if lo > base {
+			start = int(lo - base)
+		}
+		if hi < base+containerBits {
+			end = int(hi - base)
+		}
+		if c = c.fillRange(start, end, set); c != nil {
+			keys = append(keys, uint16(k))
+			containers = append(containers, c)
+		}
+	}
+	r.keys = append(keys, r.keys[i:]...)
+	r.containers = append(containers, r.containers[i:]...)
+}
+
+// rangeFrom call f with every number in r plus base in increasing order
+// return false if f return false
+func (r *roaring32) rangeFrom(base uint64, f func(x uint64) bool) bool {
+	for i, c := range r.containers {
+		if !c.rangeFrom(base+uint64(r.keys[i])<<16, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// rank return numbers in r less than or equal to x
+func (r *roaring32) rank(x uint32) uint64 {
+	n := uint64(0)
+	for i, key := range r.keys {
+		if key > uint16(x>>16) {
+			break
+		}
+		if key < uint16(x>>16) {
+			n += uint64(r.containers[i].card)
+		} else {
+			n += uint64(r.containers[i].rank(uint16(x)))
+		}
+	}
+	return n
+}
+
+// selectAt return the i-th smallest number in r, false if i is out of range
+func (r *roaring32) selectAt(i uint64) (uint32, bool) {
+	for k, c := range r.containers {
+		if i < uint64(c.card) {
+			return uint32(r.keys[k])<<16 | uint32(c.selectAt(int(i))), true
+		}
+		i -= uint64(c.card)
+	}
+	return 0, false
+}
+
+// copy return a copy bitmap
+func (r *roaring32) copy() *roaring32 {
+	new := roaring32{}
+	new.keys = append([]uint16(nil), r.keys...)
+	new.containers = make([]*container, len(r.containers))
+	for i, c := range r.containers {
+		new.containers[i] = c.copy()
+	}
+	return &new
+}
+
+// optimize replace every container with its smallest form
+func (r *roaring32) optimize() {
+	for i, c := range r.containers {
+		r.containers[i] = c.optimize()
+	}
+}
+
+// merge set r to op of r and c container by container
+// containers only in r are kept if onlyR, containers only in c are copied if onlyC
+func (r *roaring32) merge(c *roaring32, op func(x, y bitInt) bitInt, onlyR bool, onlyC bool) {
+	keys := make([]uint16, 0, len(r.keys)+len(c.keys))
+	containers := make([]*container, 0, len(r.containers)+len(c.containers))
+	i, j := 0, 0
+	for i < len(r.keys) || j < len(c.keys) {
+		switch {
+		case j >= len(c.keys) || (i < len(r.keys) && r.keys[i] < c.keys[j]):
+			if onlyR {
+				keys = append(keys, r.keys[i])
+				containers = append(containers, r.containers[i])
+			}
+			i++
+		case i >= len(r.keys) || c.keys[j] < r.keys[i]:
+			if onlyC {
+				keys = append(keys, c.keys[j])
+				containers = append(containers, c.containers[j].copy())
+			}
+			j++
+		default:
+			if new := combine(r.containers[i], c.containers[j], op); new != nil {
+				keys = append(keys, r.keys[i])
+				containers = append(containers, new)
+			}
+			i++
+			j++
+		}
+	}
+	r.keys, r.containers = keys, containers
+}
+
+// appendTo append r in the portable format to b
+func (r *roaring32) appendTo(b []byte) []byte {
+	start, size := len(b), len(r.keys)
+	hasRun := false
+	for _, c := range r.containers {
+		hasRun = hasRun || c.runs != nil
+	}
+	if hasRun {
+		b = appendUint32(b, serialCookie|uint32(size-1)<<16)
+		flags := make([]byte, (size+7)/8)
+		for i, c := range r.containers {
+			if c.runs != nil {
+				flags[i/8] |= 1 << uint(i%8)
+			}
+		}
+		b = append(b, flags...)
+	} else {
+		b = appendUint32(b, serialCookieNoRun)
+		b = appendUint32(b, uint32(size))
+	}
+	for i, c := range r.containers {
+		b = appendUint16(b, r.keys[i])
+		b = appendUint16(b, uint16(c.card-1))
+	}
+	if !hasRun || size >= noOffsetThreshold {
+		offset := len(b) - start + 4*size
+		for _, c := range r.containers {
+			b = appendUint32(b, uint32(offset))
+			offset += c.size()
+		}
+	}
+	for _, c := range r.containers {
+		b = c.appendTo(b)
+	}
+	return b
+}
+
+// readRoaring32 read a bitmap in the portable format
+// return the bitmap and bytes read
+func readRoaring32(data []byte) (*roaring32, int, error) {
+	if len(data) < 4 {
+		return nil, 0, errShortBuffer
+	}
+	cookie := binary.LittleEndian.Uint32(data)
+	var size, pos int
+	var flags []byte // flags of run containers
+	switch {
+	case cookie == serialCookieNoRun:
+		if len(data) < 8 {
+			return nil, 0, errShortBuffer
+		}
+		size, pos = int(binary.LittleEndian.Uint32(data[4:])), 8
+		if size > 1<<16 {
+			return nil, 0, errRoaring
+		}
+	case cookie&0xffff == serialCookie:
+		size, pos = int(cookie>>16)+1, 4
+		if len(data) < pos+(size+7)/8 {
+			return nil, 0, errShortBuffer
+		}
+		flags = data[pos : pos+(size+7)/8]
+		pos += len(flags)
+	default:
+		return nil, 0, errRoaring
+	}
+	if len(data) < pos+4*size {
+		return nil, 0, errShortBuffer
+	}
+	r := &roaring32{keys: make([]uint16, size), containers: make([]*container, size)}
+	cards := make([]int, size)
+	for i := range r.keys {
+		r.keys[i] = binary.LittleEndian.Uint16(data[pos+4*i:])
+		cards[i] = int(binary.LittleEndian.Uint16(data[pos+4*i+2:])) + 1
+		if i > 0 && r.keys[i] <= r.keys[i-1] {
+			return nil, 0, errRoaring
+		}
+	}
+	pos += 4 * size
+	if flags == nil || size >= noOffsetThreshold {
+		// offsets are not needed to read all containers
+		if len(data) < pos+4*size {
+			return nil, 0, errShortBuffer
+		}
+		pos += 4 * size
+	}
+	for i := range r.containers {
+		isRun := flags != nil && flags[i/8]&(1<<uint(i%8)) != 0
+		c, n, err := readContainer(data[pos:], cards[i], isRun)
+		if err != nil {
+			return nil, 0, err
+		}
+		r.containers[i] = c
+		pos += n
+	}
+	return r, pos, nil
+}
+
+// appendUint16 append v to b in little endian
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+// appendUint32 append v to b in little endian
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
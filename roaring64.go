@@ -0,0 +1,329 @@
+package bitmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Roaring64 is a roaring bitmap of uint64 numbers
+// the high 32 bits are the key of a 32 bits roaring bitmap of the low 32 bits,
+// which stores every 65536 numbers in an array, a bitmap or runs
+type Roaring64 struct {
+	keys    []uint32 // increasing
+	buckets []*roaring32
+}
+
+// NewRoaring64 return a new empty Roaring64
+func NewRoaring64() *Roaring64 {
+	return &Roaring64{}
+}
+
+// String return formated string of bitmap
+func (r *Roaring64) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	r.Range(func(x uint64) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers in bitmap
+// it's 0 if the bitmap has all 1<<64 numbers
+func (r *Roaring64) Len() uint64 {
+	n := uint64(0)
+	for _, b := range r.buckets {
+		n += b.card()
+	}
+	return n
+}
+
+// find return the index of key in keys, false if key is not found
+// the index is where key should be inserted if it's not found
+func (r *Roaring64) find(key uint32) (int, bool) {
+	i := sort.Search(len(r.keys), func(i int) bool {
+		return r.keys[i] >= key
+	})
+	return i, i < len(r.keys) && r.keys[i] == key
+}
+
+// Has return true if x is in the bitmap
+func (r *Roaring64) Has(x uint64) bool {
+	i, ok := r.find(uint32(x >> 32))
+	return ok && r.buckets[i].has(uint32(x))
+}
+
+// Add add x to the bitmap
+func (r *Roaring64) Add(x uint64) {
+	r.add(x)
+}
+
+// add add x to the bitmap, return false if x is in the bitmap
+func (r *Roaring64) add(x uint64) bool {
+	i, ok := r.find(uint32(x >> 32))
+	if !ok {
+		r.keys = append(r.keys, 0)
+		copy(r.keys[i+1:], r.keys[i:])
+		r.keys[i] = uint32(x >> 32)
+		r.buckets = append(r.buckets, nil)
+		copy(r.buckets[i+1:], r.buckets[i:])
+		r.buckets[i] = &roaring32{}
+	}
+	return r.buckets[i].add(uint32(x))
+}
+
+// Remove remove x in bitmap
+func (r *Roaring64) Remove(x uint64) {
+	r.remove(x)
+}
+
+// remove remove x in bitmap, return false if x is not in the bitmap
+func (r *Roaring64) remove(x uint64) bool {
+	i, ok := r.find(uint32(x >> 32))
+	if !ok || !r.buckets[i].remove(uint32(x)) {
+		return false
+	}
+	if len(r.buckets[i].keys) == 0 {
+		r.delete(i)
+	}
+	return true
+}
+
+// delete delete the i-th bucket
+func (r *Roaring64) delete(i int) {
+	r.keys = append(r.keys[:i], r.keys[i+1:]...)
+	copy(r.buckets[i:], r.buckets[i+1:])
+	r.buckets[len(r.buckets)-1] = nil
+	r.buckets = r.buckets[:len(r.buckets)-1]
+}
+
+// AddRange add numbers in [start, end) to the bitmap
+func (r *Roaring64) AddRange(start uint64, end uint64) {
+	r.fillRange(start, end, true)
+}
+
+// RemoveRange remove numbers in [start, end) in the bitmap
+func (r *Roaring64) RemoveRange(start uint64, end uint64) {
+	r.fillRange(start, end, false)
+}
+
+// fillRange add or remove numbers in [start, end)
+func (r *Roaring64) fillRange(start uint64, end uint64, set bool) {
+	if start >= end {
+		return
+	}
+	first, last := start>>32, (end-1)>>32
+	i, _ := r.find(uint32(first))
+	keys := append([]uint32(nil), r.keys[:i]...)
+	buckets := append([]*roaring32(nil), r.buckets[:i]...)
+	for k := first; k <= last; k++ {
+		var b *roaring32
+		if i < len(r.keys) && uint64(r.keys[i]) == k {
+			b = r.buckets[i]
+			i++
+		} else if !set {
+			// nothing to remove before the next bucket
+			if i >= len(r.keys) || uint64(r.keys[i]) > last {
+				break
+			}
+			k = uint64(r.keys[i]) - 1
+			continue
+		} else {
+			b = &roaring32{}
+		}
+		base := k << 32
+		lo, hi := uint64(0), uint64(1)<<32
+		if start > base {
+			lo = start - base
+		}
+		if end-base < hi {
+			hi = end - base
+		}
+		b.fillRange(lo, hi, set)
+		if len(b.keys) > 0 {
+			keys = append(keys, uint32(k))
+			buckets = append(buckets, b)
+		}
+		if k == last {
+			// k++ overflows if last is the largest key
+			break
+		}
+	}
+	r.keys = append(keys, r.keys[i:]...)
+	r.buckets = append(buckets, r.buckets[i:]...)
+}
+
+// Range call f with every element in increasing order
+// stop if f return false
+func (r *Roaring64) Range(f func(x uint64) bool) {
+	for i, b := range r.buckets {
+		if !b.rangeFrom(uint64(r.keys[i])<<32, f) {
+			return
+		}
+	}
+}
+
+// Rank return numbers in bitmap less than or equal to x
+func (r *Roaring64) Rank(x uint64) uint64 {
+	n := uint64(0)
+	for i, key := range r.keys {
+		if key > uint32(x>>32) {
+			break
+		}
+		if key < uint32(x>>32) {
+			n += r.buckets[i].card()
+		} else {
+			n += r.buckets[i].rank(uint32(x))
+		}
+	}
+	return n
+}
+
+// Select return the i-th smallest element from 0, false if i >= Len()
+func (r *Roaring64) Select(i uint64) (uint64, bool) {
+	for k, b := range r.buckets {
+		card := b.card()
+		if i < card {
+			x, _ := b.selectAt(i)
+			return uint64(r.keys[k])<<32 | uint64(x), true
+		}
+		i -= card
+	}
+	return 0, false
+}
+
+// RunOptimize compress every container to runs if it's smaller
+func (r *Roaring64) RunOptimize() {
+	for _, b := range r.buckets {
+		b.optimize()
+	}
+}
+
+// Clear make the bitmap empty
+func (r *Roaring64) Clear() {
+	*r = *NewRoaring64()
+}
+
+// Copy return a copy bitmap
+func (r *Roaring64) Copy() *Roaring64 {
+	new := Roaring64{}
+	new.keys = append([]uint32(nil), r.keys...)
+	new.buckets = make([]*roaring32, len(r.buckets))
+	for i, b := range r.buckets {
+		new.buckets[i] = b.copy()
+	}
+	return &new
+}
+
+// Union r = r | c
+// elements in r or c
+func (r *Roaring64) Union(c *Roaring64) {
+	r.merge(c, func(x, y bitInt) bitInt { return x | y }, true, true)
+}
+
+// Intersect r = r & c
+// elements both in r and c
+func (r *Roaring64) Intersect(c *Roaring64) {
+	r.merge(c, func(x, y bitInt) bitInt { return x & y }, false, false)
+}
+
+// Except r = r - c
+// elements only in r
+func (r *Roaring64) Except(c *Roaring64) {
+	r.merge(c, func(x, y bitInt) bitInt { return x &^ y }, true, false)
+}
+
+// SymExcept r = (r - c) | (c - r)
+// elements only in r or only in c
+func (r *Roaring64) SymExcept(c *Roaring64) {
+	r.merge(c, func(x, y bitInt) bitInt { return x ^ y }, true, true)
+}
+
+// merge set r to op of r and c bucket by bucket
+// buckets only in r are kept if onlyR, buckets only in c are copied if onlyC
+func (r *Roaring64) merge(c *Roaring64, op func(x, y bitInt) bitInt, onlyR bool, onlyC bool) {
+	keys := make([]uint32, 0, len(r.keys)+len(c.keys))
+	buckets := make([]*roaring32, 0, len(r.buckets)+len(c.buckets))
+	i, j := 0, 0
+	for i < len(r.keys) || j < len(c.keys) {
+		switch {
+		case j >= len(c.keys) || (i < len(r.keys) && r.keys[i] < c.keys[j]):
+			if onlyR {
+				keys = append(keys, r.keys[i])
+				buckets = append(buckets, r.buckets[i])
+			}
+			i++
+		case i >= len(r.keys) || c.keys[j] < r.keys[i]:
+			if onlyC {
+				keys = append(keys, c.keys[j])
+				buckets = append(buckets, c.buckets[j].copy())
+			}
+			j++
+		default:
+			b := r.buckets[i]
+			b.merge(c.buckets[j], op, onlyR, onlyC)
+			if len(b.keys) > 0 {
+				keys = append(keys, r.keys[i])
+				buckets = append(buckets, b)
+			}
+			i++
+			j++
+		}
+	}
+	r.keys, r.buckets = keys, buckets
+}
+
+// MarshalBinary encode the bitmap in the 64 bits roaring portable format:
+// the number of buckets as uint64, then the key as uint32 and
+// the 32 bits portable format of every bucket, all in little endian
+func (r *Roaring64) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(len(r.keys)))
+	for i, bucket := range r.buckets {
+		b = appendUint32(b, r.keys[i])
+		b = bucket.appendTo(b)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decode data in the 64 bits roaring portable format into the bitmap
+func (r *Roaring64) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return errShortBuffer
+	}
+	size := binary.LittleEndian.Uint64(data)
+	data = data[8:]
+	if size > uint64(len(data))/12 {
+		return errRoaring
+	}
+	new := Roaring64{}
+	prev := uint32(0)
+	for i := 0; i < int(size); i++ {
+		if len(data) < 4 {
+			return errShortBuffer
+		}
+		key := binary.LittleEndian.Uint32(data)
+		if i > 0 && key <= prev {
+			return errRoaring
+		}
+		prev = key
+		bucket, n, err := readRoaring32(data[4:])
+		if err != nil {
+			return err
+		}
+		data = data[4+n:]
+		if len(bucket.keys) > 0 {
+			new.keys = append(new.keys, key)
+			new.buckets = append(new.buckets, bucket)
+		}
+	}
+	*r = new
+	return nil
+}
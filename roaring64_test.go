@@ -0,0 +1,241 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"bytes"
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestRoaring64(t *testing.T) {
+	r := bitmap.NewRoaring64()
+	for _, x := range []uint64{5, 1 << 40, 3, math.MaxUint64, 1<<32 + 7, 5} {
+		r.Add(x)
+	}
+	expected := "{3 5 4294967303 1099511627776 18446744073709551615}"
+	if r.String() != expected || r.Len() != 5 {
+		t.Errorf("TestRoaring64 failed. Expected %s, Got %s", expected, r.String())
+	}
+	if !r.Has(1<<32+7) || r.Has(7) || !r.Has(math.MaxUint64) {
+		t.Errorf("TestRoaring64 Has failed. Got %s", r.String())
+	}
+	r.Remove(5)
+	r.Remove(6)
+	r.Remove(math.MaxUint64)
+	if r.String() != "{3 4294967303 1099511627776}" {
+		t.Errorf("TestRoaring64 Remove failed. Expected {3 4294967303 1099511627776}, Got %s", r.String())
+	}
+	// an array container becomes a bitmap and back
+	for i := uint64(0); i < 10000; i++ {
+		r.Add(1<<40 + i*3)
+	}
+	for i := uint64(0); i < 10000; i += 2 {
+		r.Remove(1<<40 + i*3)
+	}
+	if r.Len() != 5002 || r.Has(1<<40) || !r.Has(1<<40+3) || r.Has(1<<40+4) {
+		t.Errorf("TestRoaring64 failed. Expected 5002, Got %d", r.Len())
+	}
+	if rank := r.Rank(1<<40 + 3*101); rank != 2+51 {
+		t.Errorf("TestRoaring64 Rank failed. Expected 53, Got %d", rank)
+	}
+	if x, ok := r.Select(2 + 50); !ok || x != 1<<40+3*101 {
+		t.Errorf("TestRoaring64 Select failed. Expected %d, Got %d", uint64(1<<40+3*101), x)
+	}
+	if _, ok := r.Select(5002); ok {
+		t.Errorf("TestRoaring64 Select failed. Expected false out of range")
+	}
+	r.Clear()
+	if r.Len() != 0 || r.String() != "{}" {
+		t.Errorf("TestRoaring64 Clear failed. Got %s", r.String())
+	}
+}
+
+func TestRoaring64Range(t *testing.T) {
+	r := bitmap.NewRoaring64()
+	r.AddRange(1<<32-3, 1<<32+2)
+	if r.String() != "{4294967293 4294967294 4294967295 4294967296 4294967297}" {
+		t.Errorf("TestRoaring64Range failed. Got %s", r.String())
+	}
+	r.AddRange(1<<20, 1<<36)
+	if r.Len() != 1<<36-1<<20 || !r.Has(1<<35) || r.Has(1<<36) {
+		t.Errorf("TestRoaring64Range AddRange failed. Expected %d, Got %d", uint64(1<<36-1<<20), r.Len())
+	}
+	r.RemoveRange(1<<20+10, 1<<36-10)
+	if r.Len() != 20 || r.Rank(1<<36) != 20 || r.Rank(1<<20+9) != 10 {
+		t.Errorf("TestRoaring64Range RemoveRange failed. Expected 20, Got %d", r.Len())
+	}
+	if x, ok := r.Select(10); !ok || x != 1<<36-10 {
+		t.Errorf("TestRoaring64Range Select failed. Expected %d, Got %d", uint64(1<<36-10), x)
+	}
+	r.AddRange(math.MaxUint64-3, math.MaxUint64)
+	r.Add(math.MaxUint64)
+	r.RemoveRange(0, math.MaxUint64-1)
+	if r.String() != "{18446744073709551614 18446744073709551615}" {
+		t.Errorf("TestRoaring64Range failed. Got %s", r.String())
+	}
+	// a run container gets an element next to the run
+	r.AddRange(100, 200)
+	r.Add(50)
+	r.Remove(150)
+	if r.Len() != 102 || r.Has(150) || !r.Has(50) || !r.Has(199) || r.Rank(199) != 100 {
+		t.Errorf("TestRoaring64Range failed. Expected 102, Got %d", r.Len())
+	}
+}
+
+func TestRoaring64Sets(t *testing.T) {
+	b := bitmap.NewRoaring64()
+	c := bitmap.NewRoaring64()
+	in := map[uint64]int{} // 1 for b, 2 for c
+	x := uint64(1)
+	for i := 0; i < 30000; i++ {
+		x = x*6364136223846793005 + 1442695040888963407
+		// dense, sparse and huge numbers
+		v := []uint64{x % 20000, x % (1 << 34), x | 1<<63}[i%3]
+		if i%2 == 0 {
+			b.Add(v)
+			in[v] |= 1
+		} else {
+			c.Add(v)
+			in[v] |= 2
+		}
+	}
+	b.AddRange(1000, 90000)
+	c.AddRange(50000, 150000)
+	for v := uint64(1000); v < 150000; v++ {
+		if v < 90000 {
+			in[v] |= 1
+		}
+		if v >= 50000 {
+			in[v] |= 2
+		}
+	}
+	cases := []struct {
+		name string
+		op   func(b, c *bitmap.Roaring64)
+		keep func(flags int) bool
+	}{
+		{"Union", (*bitmap.Roaring64).Union, func(flags int) bool { return flags != 0 }},
+		{"Intersect", (*bitmap.Roaring64).Intersect, func(flags int) bool { return flags == 3 }},
+		{"Except", (*bitmap.Roaring64).Except, func(flags int) bool { return flags == 1 }},
+		{"SymExcept", (*bitmap.Roaring64).SymExcept, func(flags int) bool { return flags == 1 || flags == 2 }},
+	}
+	for _, tc := range cases {
+		var expected []uint64
+		for v, flags := range in {
+			if tc.keep(flags) {
+				expected = append(expected, v)
+			}
+		}
+		sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+		bb := b.Copy()
+		tc.op(bb, c)
+		i := 0
+		bb.Range(func(v uint64) bool {
+			if i >= len(expected) || v != expected[i] {
+				t.Fatalf("TestRoaring64Sets %s failed at %d. Got %d", tc.name, i, v)
+			}
+			i++
+			return true
+		})
+		if i != len(expected) || bb.Len() != uint64(len(expected)) {
+			t.Errorf("TestRoaring64Sets %s failed. Expected %d, Got %d", tc.name, len(expected), i)
+		}
+	}
+	b.SymExcept(b)
+	if b.Len() != 0 {
+		t.Errorf("TestRoaring64Sets SymExcept failed. Expected {}, Got %d", b.Len())
+	}
+}
+
+func TestRoaring64SetsArrays(t *testing.T) {
+	// arrays are merged without bitmaps, results must be the same containers
+	for _, tc := range []struct {
+		name      string
+		step, max int
+		expected  func(r *bitmap.Roaring64)
+	}{
+		{"runs", 2, 8192, func(r *bitmap.Roaring64) { r.AddRange(0, 8192); r.RunOptimize() }},
+		{"bitmap", 3, 9000, func(r *bitmap.Roaring64) {
+			for v := 0; v < 9000; v++ {
+				if v%3 != 2 {
+					r.Add(uint64(v))
+				}
+			}
+		}},
+	} {
+		b, c, expected := bitmap.NewRoaring64(), bitmap.NewRoaring64(), bitmap.NewRoaring64()
+		for v := 0; v < tc.max; v += tc.step {
+			b.Add(uint64(v))
+			c.Add(uint64(v + 1))
+		}
+		b.Union(c)
+		tc.expected(expected)
+		got, _ := b.MarshalBinary()
+		want, _ := expected.MarshalBinary()
+		if !bytes.Equal(got, want) {
+			t.Errorf("TestRoaring64SetsArrays %s failed. Expected %d bytes, Got %d", tc.name, len(want), len(got))
+		}
+	}
+}
+
+func TestRoaring64Binary(t *testing.T) {
+	r := bitmap.NewRoaring64()
+	r.Add(1)
+	r.Add(2)
+	r.Add(1<<32 + 5)
+	data, _ := r.MarshalBinary()
+	// bytes of the portable format
+	expected := []byte{
+		2, 0, 0, 0, 0, 0, 0, 0, // 2 buckets
+		0, 0, 0, 0, 0x3a, 0x30, 0, 0, 1, 0, 0, 0, 0, 0, 1, 0, 16, 0, 0, 0, 1, 0, 2, 0,
+		1, 0, 0, 0, 0x3a, 0x30, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 16, 0, 0, 0, 5, 0,
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("TestRoaring64Binary failed. Expected %v, Got %v", expected, data)
+	}
+	r.Clear()
+	r.AddRange(0, 100)
+	data, _ = r.MarshalBinary()
+	expected = []byte{
+		1, 0, 0, 0, 0, 0, 0, 0, // 1 bucket
+		0, 0, 0, 0, 0x3b, 0x30, 0, 0, 1, 0, 0, 99, 0, 1, 0, 0, 0, 99, 0,
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("TestRoaring64Binary runs failed. Expected %v, Got %v", expected, data)
+	}
+
+	// arrays, bitmaps and runs with the offset header
+	for i := uint64(0); i < 10000; i++ {
+		r.Add(1<<16 + i*5)
+		r.Add(3<<16 + i)
+		r.Add(1<<50 + i*1000)
+	}
+	r.AddRange(5<<16, 9<<16)
+	r.RunOptimize()
+	data, err := r.MarshalBinary()
+	c := bitmap.NewRoaring64()
+	if err != nil || c.UnmarshalBinary(data) != nil || c.String() != r.String() || c.Len() != r.Len() {
+		t.Errorf("TestRoaring64Binary failed. Expected %d elements, Got %d", r.Len(), c.Len())
+	}
+	for _, n := range []int{0, 7, 20, len(data) - 1} {
+		if c.UnmarshalBinary(data[:n]) == nil {
+			t.Errorf("TestRoaring64Binary failed. Expected error for %d bytes", n)
+		}
+	}
+	data[12] = 0
+	if c.UnmarshalBinary(data) == nil {
+		t.Errorf("TestRoaring64Binary failed. Expected error for invalid cookie")
+	}
+}
+
+func BenchmarkRoaring64(b *testing.B) {
+	r := bitmap.NewRoaring64()
+	const memory = 100000000
+	for i := 0; i < b.N; i++ {
+		x := uint64(i%memory) << 8
+		r.Add(x)
+		r.Has(x)
+		r.Remove(x)
+	}
+}
@@ -0,0 +1,163 @@
+package bitmap
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// SRCBitmap is a bitSet count in [start, end) with signed counts
+// every count is stored as a two's-complement field
+type SRCBitmap struct {
+	len        int
+	n          int
+	start, end int
+	bitSize    int
+	mask       bitInt
+	numSize    int
+	words      []bitInt
+}
+
+// NewSRC return a new bitmap count [start, end)
+// counts are kept in [-n, n]
+func NewSRC(start int, end int, n int) *SRCBitmap {
+	if n <= 0 || uint64(n) > 1<<(bitSize-2)-1 || start >= end {
+		return nil
+	}
+	numSize := int(math.Log2(float64(n)))
+	src := SRCBitmap{}
+	src.len = 0
+	src.n = n
+	src.start, src.end = start, end
+	// one more bit for sign
+	src.numSize = numSize + 2
+	src.bitSize = bitSize / src.numSize
+	src.mask = bitInt(1)<<bitInt(src.numSize) - 1
+	src.words = make([]bitInt, bitmapSize)
+	return &src
+}
+
+// String return formated string of bitmap
+func (src *SRCBitmap) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	src.Range(func(x int, count int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// Len return numbers with nonzero count in bitmap
+func (src *SRCBitmap) Len() int {
+	return src.len
+}
+
+// Has return true if count of x is not zero
+func (src *SRCBitmap) Has(x int) bool {
+	return src.Count(x) != 0
+}
+
+// Add add delta to count of x, delta can be negative
+// the count is kept in [-n, n]
+func (src *SRCBitmap) Add(x int, delta int) {
+	if x < src.start || x >= src.end || delta == 0 {
+		return
+	}
+	x -= src.start
+	word, bit := x/src.bitSize, bitInt(x%src.bitSize*src.numSize)
+	if word >= len(src.words) {
+		src.words = append(src.words, make([]bitInt, word+1-len(src.words))...)
+	}
+	old := src.field(src.words[word], bit)
+	count := old + delta
+	if count > src.n || (delta > 0 && count < old) {
+		count = src.n
+	} else if count < -src.n || (delta < 0 && count > old) {
+		count = -src.n
+	}
+	if old == 0 && count != 0 {
+		src.len++
+	} else if old != 0 && count == 0 {
+		src.len--
+	}
+	numSize := src.mask << bit
+	src.words[word] = src.words[word]&^numSize | (bitInt(count)&src.mask)<<bit
+}
+
+// Count return the signed count of x
+func (src *SRCBitmap) Count(x int) int {
+	if x < src.start || x >= src.end {
+		return 0
+	}
+	x -= src.start
+	word, bit := x/src.bitSize, bitInt(x%src.bitSize*src.numSize)
+	if word >= len(src.words) {
+		return 0
+	}
+	return src.field(src.words[word], bit)
+}
+
+// RemoveAll set count of x to zero
+func (src *SRCBitmap) RemoveAll(x int) {
+	if x < src.start || x >= src.end {
+		return
+	}
+	x -= src.start
+	word, bit := x/src.bitSize, bitInt(x%src.bitSize*src.numSize)
+	if word < len(src.words) {
+		numSize := src.mask << bit
+		if src.words[word]&numSize != 0 {
+			src.len--
+			src.words[word] &^= numSize
+		}
+	}
+}
+
+// Range call f with every element and its count in increasing order
+// elements with zero count are skipped, stop if f return false
+func (src *SRCBitmap) Range(f func(x int, count int) bool) {
+	for i, word := range src.words {
+		if word == 0 {
+			continue
+		}
+		for j := 0; j < src.bitSize; j++ {
+			count := src.field(word, bitInt(j*src.numSize))
+			if count != 0 && !f(src.start+src.bitSize*i+j, count) {
+				return
+			}
+		}
+	}
+}
+
+// Clear make the bitmap empty
+func (src *SRCBitmap) Clear() {
+	*src = *NewSRC(src.start, src.end, src.n)
+}
+
+// Copy return a copy bitmap
+func (src *SRCBitmap) Copy() *SRCBitmap {
+	new := SRCBitmap{}
+	new.len = src.len
+	new.n = src.n
+	new.start, new.end = src.start, src.end
+	new.mask = src.mask
+	new.bitSize = src.bitSize
+	new.numSize = src.numSize
+	new.words = make([]bitInt, len(src.words))
+	copy(new.words, src.words)
+	return &new
+}
+
+// field return the signed value of the field start at bit of word
+func (src *SRCBitmap) field(word bitInt, bit bitInt) int {
+	v := int((word >> bit) & src.mask)
+	if v&(1<<bitInt(src.numSize-1)) != 0 {
+		v -= int(src.mask) + 1
+	}
+	return v
+}
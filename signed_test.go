@@ -0,0 +1,82 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestSRCAdd(t *testing.T) {
+	b := bitmap.NewSRC(-1, 100, 5)
+	b.Add(-2, 1)
+	b.Add(-1, 3)
+	b.Add(0, -2)
+	b.Add(1, 1)
+	b.Add(1, -1)
+	b.Add(2, 2)
+	b.Add(2, -4)
+	b.Add(100, 1)
+	if b.Len() != 3 || b.String() != "{-1 0 2}" {
+		t.Errorf("TestSRCAdd failed. Expected {-1 0 2}, Got %s", b.String())
+	}
+	if b.Count(-1) != 3 || b.Count(0) != -2 || b.Count(1) != 0 || b.Count(2) != -2 {
+		t.Errorf("TestSRCAdd Count failed.")
+	}
+	if b.Has(-2) || !b.Has(0) || b.Has(1) || b.Has(100) {
+		t.Errorf("TestSRCAdd Has failed.")
+	}
+}
+
+func TestSRCSaturate(t *testing.T) {
+	b := bitmap.NewSRC(0, 10, 5)
+	b.Add(3, 4)
+	b.Add(3, 4)
+	b.Add(4, -100)
+	b.Add(5, 7)
+	b.Add(5, -7)
+	if b.Count(3) != 5 || b.Count(4) != -5 || b.Count(5) != -2 || b.Count(2) != 0 {
+		t.Errorf("TestSRCSaturate failed. Got %d %d %d", b.Count(3), b.Count(4), b.Count(5))
+	}
+}
+
+func TestSRCRange(t *testing.T) {
+	b := bitmap.NewSRC(10, 1000, 100)
+	b.Add(999, -7)
+	b.Add(10, 5)
+	b.Add(500, 1)
+	b.Add(500, -1)
+	b.Add(42, 100)
+	var xs, counts []int
+	b.Range(func(x int, count int) bool {
+		xs = append(xs, x)
+		counts = append(counts, count)
+		return true
+	})
+	if len(xs) != 3 || xs[0] != 10 || xs[1] != 42 || xs[2] != 999 ||
+		counts[0] != 5 || counts[1] != 100 || counts[2] != -7 {
+		t.Errorf("TestSRCRange failed. Got %v %v", xs, counts)
+	}
+	n := 0
+	b.Range(func(x int, count int) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("TestSRCRange stop failed.")
+	}
+}
+
+func TestSRCRemoveAll(t *testing.T) {
+	b := bitmap.NewSRC(0, 10, 3)
+	b.Add(1, -3)
+	b.Add(2, 2)
+	b.RemoveAll(1)
+	b.RemoveAll(3)
+	if b.Count(1) != 0 || b.Len() != 1 {
+		t.Errorf("TestSRCRemoveAll failed.")
+	}
+	c := b.Copy()
+	b.Clear()
+	if b.Len() != 0 || c.Count(2) != 2 {
+		t.Errorf("TestSRCRemoveAll Copy failed.")
+	}
+}
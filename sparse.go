@@ -0,0 +1,174 @@
+package bitmap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// rankBlock is the number of words counted by every entry of the rank index
+const rankBlock = 8
+
+// SparseArray map non negative integer keys to values
+// keys are kept in a NBitmap and values in a slice ordered by key,
+// the value of a key is at the rank of the key
+type SparseArray[V any] struct {
+	keys   *NBitmap
+	values []V
+	ranks  []int // number of keys before every rankBlock words, nil if it must be rebuilt
+	far    []int // number of keys before every far page of keys, nil if it must be rebuilt
+}
+
+// NewSparse return a new empty SparseArray
+func NewSparse[V any]() *SparseArray[V] {
+	return &SparseArray[V]{
+		keys: New(),
+	}
+}
+
+// Len return numbers of keys
+func (s *SparseArray[V]) Len() int {
+	return len(s.values)
+}
+
+// Has return true if x is a key
+func (s *SparseArray[V]) Has(x int) bool {
+	return s.keys.Has(x)
+}
+
+// Get return the value of x, false if x is not a key
+func (s *SparseArray[V]) Get(x int) (V, bool) {
+	if !s.keys.Has(x) {
+		var zero V
+		return zero, false
+	}
+	return s.values[s.rank(x)], true
+}
+
+// Set set the value of x to v
+func (s *SparseArray[V]) Set(x int, v V) {
+	if x < 0 {
+		return
+	}
+	if s.keys.Has(x) {
+		s.values[s.rank(x)] = v
+		return
+	}
+	size, far := s.keys.wordLen(), len(s.keys.farKeys)
+	s.keys.Add(x)
+	s.update(x, size, far, 1)
+	i := s.rank(x)
+	var zero V
+	s.values = append(s.values, zero)
+	copy(s.values[i+1:], s.values[i:])
+	s.values[i] = v
+}
+
+// Delete remove x and its value
+func (s *SparseArray[V]) Delete(x int) {
+	if !s.keys.Has(x) {
+		return
+	}
+	i := s.rank(x)
+	size, far := s.keys.wordLen(), len(s.keys.farKeys)
+	s.keys.Remove(x)
+	s.update(x, size, far, -1)
+	copy(s.values[i:], s.values[i+1:])
+	var zero V
+	s.values[len(s.values)-1] = zero
+	s.values = s.values[:len(s.values)-1]
+}
+
+// Range call f with every key and its value in increasing order of keys
+// stop if f return false
+func (s *SparseArray[V]) Range(f func(x int, v V) bool) {
+	i := 0
+	s.keys.Range(func(x int) bool {
+		i++
+		return f(x, s.values[i-1])
+	})
+}
+
+// Keys return a copy of the keys
+func (s *SparseArray[V]) Keys() *NBitmap {
+	return s.keys.Copy()
+}
+
+// Clear make the array empty
+func (s *SparseArray[V]) Clear() {
+	*s = *NewSparse[V]()
+}
+
+// Copy return a copy array, values are copied by assignment
+func (s *SparseArray[V]) Copy() *SparseArray[V] {
+	new := SparseArray[V]{}
+	new.keys = s.keys.Copy()
+	new.values = append([]V(nil), s.values...)
+	new.ranks = append([]int(nil), s.ranks...)
+	new.far = append([]int(nil), s.far...)
+	return &new
+}
+
+// update update the rank index after x is added (delta 1) or removed (delta -1)
+// the index is dropped if the page table of keys is resized from size words,
+// the index of far pages is dropped if far pages are added or released from far pages
+func (s *SparseArray[V]) update(x int, size int, far int, delta int) {
+	if s.keys.wordLen() != size {
+		s.ranks, s.far = nil, nil
+		return
+	}
+	if len(s.keys.farKeys) != far {
+		s.far = nil
+	}
+	for b := x/bitSize/rankBlock + 1; b < len(s.ranks); b++ {
+		s.ranks[b] += delta
+	}
+	for i := len(s.far) - 1; i >= 0 && s.keys.farKeys[i] > x/pageBits; i-- {
+		s.far[i] += delta
+	}
+}
+
+// index rebuild the rank index of the page table of keys
+func (s *SparseArray[V]) index() {
+	s.ranks = make([]int, s.keys.wordLen()/rankBlock)
+	r := 0
+	for b := range s.ranks {
+		s.ranks[b] = r
+		if pg := s.keys.pages[b*rankBlock/pageWords]; pg != nil {
+			r += popcount(pg.words[b*rankBlock%pageWords:][:rankBlock])
+		}
+	}
+}
+
+// indexFar rebuild the rank index of far pages of keys
+func (s *SparseArray[V]) indexFar() {
+	s.far = make([]int, len(s.keys.farKeys))
+	r := s.keys.len
+	for i := len(s.far) - 1; i >= 0; i-- {
+		r -= s.keys.far[s.keys.farKeys[i]].count
+		s.far[i] = r
+	}
+}
+
+// rank return number of keys less than x, x must be a key
+func (s *SparseArray[V]) rank(x int) int {
+	word, bit := x/bitSize, x%bitSize
+	var r, from int
+	if word < s.keys.wordLen() {
+		if s.ranks == nil {
+			s.index()
+		}
+		block := word / rankBlock
+		r, from = s.ranks[block], block*rankBlock%pageWords
+	} else {
+		if s.far == nil {
+			s.indexFar()
+		}
+		r = s.far[sort.SearchInts(s.keys.farKeys, word/pageWords)]
+	}
+	// a block never cross pages, and the page of a key is always allocated
+	pg := s.keys.lookup(word / pageWords)
+	for _, w := range pg.words[from : word%pageWords] {
+		r += bits.OnesCount64(uint64(w))
+	}
+	return r + bits.OnesCount64(uint64(pg.words[word%pageWords]&(1<<bitInt(bit)-1)))
+}
@@ -0,0 +1,116 @@
+package bitmap_test
+
+import (
+	"bitmap"
+	"testing"
+)
+
+func TestSparseSet(t *testing.T) {
+	s := bitmap.NewSparse[string]()
+	s.Set(100000, "c")
+	s.Set(5, "a")
+	s.Set(700, "b")
+	s.Set(-1, "x")
+	s.Set(5, "A")
+	s.Set(1<<30, "e")
+	s.Set(1<<29, "d")
+	if s.Len() != 5 {
+		t.Errorf("TestSparseSet failed. Expected 5, Got %d", s.Len())
+	}
+	for x, expected := range map[int]string{5: "A", 700: "b", 100000: "c", 1 << 29: "d", 1 << 30: "e"} {
+		if v, ok := s.Get(x); !ok || v != expected {
+			t.Errorf("TestSparseSet failed for %d. Expected %s, Got %s", x, expected, v)
+		}
+	}
+	if _, ok := s.Get(6); ok || s.Has(-1) {
+		t.Errorf("TestSparseSet failed. Expected missing key")
+	}
+}
+
+func TestSparseDelete(t *testing.T) {
+	s := bitmap.NewSparse[int]()
+	for i := 0; i < 5000; i += 3 {
+		s.Set(i, i*10)
+	}
+	for i := 0; i < 5000; i += 6 {
+		s.Delete(i)
+	}
+	s.Delete(1)
+	for i := 0; i < 5000; i++ {
+		v, ok := s.Get(i)
+		if ok != (i%3 == 0 && i%6 != 0) || (ok && v != i*10) {
+			t.Fatalf("TestSparseDelete failed for %d. Got %d %v", i, v, ok)
+		}
+	}
+	if s.Len() != 833 {
+		t.Errorf("TestSparseDelete failed. Expected 833, Got %d", s.Len())
+	}
+	// far keys, deleted and set again between lookups
+	for i := 1; i <= 50; i++ {
+		s.Set(i<<24, -i)
+		s.Get(i << 24)
+	}
+	for i := 2; i <= 50; i += 2 {
+		s.Delete(i << 24)
+		s.Get(1 << 24)
+	}
+	s.Set(6, 60)
+	s.Delete(9)
+	for i := 1; i <= 50; i++ {
+		v, ok := s.Get(i << 24)
+		if ok != (i%2 == 1) || (ok && v != -i) {
+			t.Fatalf("TestSparseDelete far failed for %d. Got %d %v", i, v, ok)
+		}
+	}
+	if v, ok := s.Get(6); !ok || v != 60 || s.Has(9) || s.Len() != 858 {
+		t.Errorf("TestSparseDelete far failed. Expected 858, Got %d", s.Len())
+	}
+}
+
+func TestSparseRange(t *testing.T) {
+	s := bitmap.NewSparse[int]()
+	s.Set(3000, 3)
+	s.Set(10, 1)
+	s.Set(64, 2)
+	var keys, values []int
+	s.Range(func(x int, v int) bool {
+		keys = append(keys, x)
+		values = append(values, v)
+		return true
+	})
+	if len(keys) != 3 || keys[0] != 10 || keys[1] != 64 || keys[2] != 3000 ||
+		values[0] != 1 || values[1] != 2 || values[2] != 3 {
+		t.Errorf("TestSparseRange failed. Got %v %v", keys, values)
+	}
+	if s.Keys().String() != "{10 64 3000}" {
+		t.Errorf("TestSparseRange Keys failed. Got %s", s.Keys().String())
+	}
+	c := s.Copy()
+	s.Clear()
+	if s.Len() != 0 || c.Len() != 3 {
+		t.Errorf("TestSparseRange Copy failed.")
+	}
+}
+
+func BenchmarkSparseGet(b *testing.B) {
+	s := bitmap.NewSparse[int]()
+	for i := 0; i < 1000000; i += 17 {
+		s.Set(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Get(i % 1000000)
+	}
+}
+
+func BenchmarkSparseGetFar(b *testing.B) {
+	s := bitmap.NewSparse[int]()
+	// every key is in its own far page
+	for i := 1; i <= 1000; i++ {
+		s.Set(i<<20, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Get((i%1000 + 1) << 20)
+	}
+}